@@ -38,6 +38,7 @@ package adbc
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
 	"github.com/apache/arrow/go/v12/arrow"
@@ -64,6 +65,41 @@ func (e Error) Error() string {
 	return fmt.Sprintf("%s: SqlState: %s, msg: %s", e.Code, string(e.SqlState[:]), e.Msg)
 }
 
+// errorJSON is the serialized form of Error. The status is encoded as
+// its numeric value, which is fixed by the ADBC specification.
+type errorJSON struct {
+	Code       Status `json:"code"`
+	Msg        string `json:"message"`
+	VendorCode int32  `json:"vendor_code,omitempty"`
+	SqlState   string `json:"sql_state,omitempty"`
+}
+
+// MarshalJSON encodes the error as a JSON object with the fields
+// "code", "message", "vendor_code" and "sql_state". Unset vendor codes
+// and SQLSTATEs are omitted.
+func (e Error) MarshalJSON() ([]byte, error) {
+	out := errorJSON{Code: e.Code, Msg: e.Msg, VendorCode: e.VendorCode}
+	if e.SqlState != [5]byte{} {
+		out.SqlState = string(e.SqlState[:])
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes an error encoded by MarshalJSON.
+func (e *Error) UnmarshalJSON(data []byte) error {
+	var in errorJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	if len(in.SqlState) > len(e.SqlState) {
+		return fmt.Errorf("adbc: invalid SQLSTATE %q, must be at most %d bytes", in.SqlState, len(e.SqlState))
+	}
+
+	*e = Error{Msg: in.Msg, Code: in.Code, VendorCode: in.VendorCode}
+	copy(e.SqlState[:], in.SqlState)
+	return nil
+}
+
 // Status represents an error code for operations that may fail
 type Status uint8
 
@@ -0,0 +1,83 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package adbc_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorJSONRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		err  adbc.Error
+		json string
+	}{
+		{
+			name: "all fields",
+			err: adbc.Error{
+				Msg:        "relation \"foo\" does not exist",
+				Code:       adbc.StatusNotFound,
+				VendorCode: 7,
+				SqlState:   [5]byte{'4', '2', 'P', '0', '1'},
+			},
+			json: `{"code":3,"message":"relation \"foo\" does not exist","vendor_code":7,"sql_state":"42P01"}`,
+		},
+		{
+			name: "no vendor code or sqlstate",
+			err:  adbc.Error{Msg: "timed out", Code: adbc.StatusTimeout},
+			json: `{"code":12,"message":"timed out"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.err)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.json, string(data))
+
+			var out adbc.Error
+			require.NoError(t, json.Unmarshal(data, &out))
+			assert.Equal(t, tt.err, out)
+		})
+	}
+}
+
+func TestErrorJSONWrapped(t *testing.T) {
+	in := struct {
+		Err *adbc.Error `json:"error"`
+	}{&adbc.Error{Msg: "denied", Code: adbc.StatusUnauthorized}}
+
+	data, err := json.Marshal(in)
+	require.NoError(t, err)
+
+	in.Err = nil
+	require.NoError(t, json.Unmarshal(data, &in))
+	require.NotNil(t, in.Err)
+	assert.Equal(t, adbc.Error{Msg: "denied", Code: adbc.StatusUnauthorized}, *in.Err)
+}
+
+func TestErrorJSONInvalidSqlState(t *testing.T) {
+	var out adbc.Error
+	err := json.Unmarshal([]byte(`{"code":1,"message":"x","sql_state":"TOOLONG"}`), &out)
+	assert.ErrorContains(t, err, "invalid SQLSTATE")
+}
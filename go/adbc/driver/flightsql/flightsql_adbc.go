@@ -36,15 +36,19 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"math"
+	"net"
 	"net/url"
 	"regexp"
 	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/apache/arrow-adbc/go/adbc"
@@ -57,10 +61,13 @@ import (
 	"github.com/apache/arrow/go/v12/arrow/memory"
 	"github.com/bluele/gcache"
 	"golang.org/x/exp/maps"
+	"golang.org/x/sync/semaphore"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -72,15 +79,42 @@ const (
 	OptionSSLRootCerts        = "adbc.flight.sql.client_option.tls_root_certs"
 	OptionWithBlock           = "adbc.flight.sql.client_option.with_block"
 	OptionWithMaxMsgSize      = "adbc.flight.sql.client_option.with_max_msg_size"
+	OptionUserAgent           = "adbc.flight.sql.client.user_agent"
 	OptionAuthorizationHeader = "adbc.flight.sql.authorization_header"
 	OptionTimeoutFetch        = "adbc.flight.sql.rpc.timeout_seconds.fetch"
 	OptionTimeoutQuery        = "adbc.flight.sql.rpc.timeout_seconds.query"
 	OptionTimeoutUpdate       = "adbc.flight.sql.rpc.timeout_seconds.update"
 	OptionRPCCallHeaderPrefix = "adbc.flight.sql.rpc.call_header."
-	infoDriverName            = "ADBC Flight SQL Driver - Go"
+	// gRPC service config (as inline JSON) to use by default, e.g. to
+	// configure retry policies
+	OptionServiceConfig = "adbc.flight.sql.rpc.service_config"
+	// Comma-separated list of host=replacement pairs used to rewrite
+	// the host of FlightEndpoint locations before connecting to them.
+	// Either side may include a port; a host without a port matches any
+	// port, which is kept unless the replacement has its own.
+	OptionLocationRewrite = "adbc.flight.sql.location.rewrite"
+	// Fetch all FlightEndpoints from the database's original location,
+	// ignoring any locations returned by the server
+	OptionLocationReuseConnection = "adbc.flight.sql.location.reuse_connection"
+	// Comma-separated list of location schemes, in order of preference.
+	// Locations with other schemes are never used.
+	OptionLocationSchemePreference = "adbc.flight.sql.location.scheme_preference"
+	// Share a single gRPC channel between all connections opened from
+	// the same database
+	OptionChannelShared = "adbc.flight.sql.channel.shared"
+	// How long (in floating-point seconds) the shared channel stays open
+	// once no connection uses it
+	OptionChannelIdleTimeout = "adbc.flight.sql.channel.idle_timeout"
+	// Maximum number of result streams (DoGet calls) open at the same
+	// time on a single gRPC channel
+	OptionMaxConcurrentStreams = "adbc.flight.sql.rpc.max_concurrent_streams"
+	infoDriverName             = "ADBC Flight SQL Driver - Go"
 )
 
 var (
+	// infoDriverVersion can be set at build time with
+	// -ldflags "-X github.com/apache/arrow-adbc/go/adbc/driver/flightsql.infoDriverVersion=<version>",
+	// otherwise it is taken from the module build info if available.
 	infoDriverVersion      string
 	infoDriverArrowVersion string
 )
@@ -92,15 +126,26 @@ var errNoTransactionSupport = adbc.Error{
 
 func init() {
 	if info, ok := debug.ReadBuildInfo(); ok {
-		for _, dep := range info.Deps {
-			switch {
-			case dep.Path == "github.com/apache/arrow-adbc/go/adbc/driver/flightsql":
-				infoDriverVersion = dep.Version
-			case strings.HasPrefix(dep.Path, "github.com/apache/arrow/go/"):
-				infoDriverArrowVersion = dep.Version
+		infoDriverVersion, infoDriverArrowVersion = versionsFromBuildInfo(info, infoDriverVersion)
+	}
+}
+
+// versionsFromBuildInfo returns the driver and Arrow versions recorded
+// in the build info. A driver version that is already set (e.g. with
+// -ldflags) is kept.
+func versionsFromBuildInfo(info *debug.BuildInfo, driverVersion string) (string, string) {
+	var arrowVersion string
+	for _, dep := range info.Deps {
+		switch {
+		case dep.Path == "github.com/apache/arrow-adbc/go/adbc":
+			if driverVersion == "" {
+				driverVersion = dep.Version
 			}
+		case strings.HasPrefix(dep.Path, "github.com/apache/arrow/go/"):
+			arrowVersion = dep.Version
 		}
 	}
+	return driverVersion, arrowVersion
 }
 
 func getTimeoutOptionValue(v string) (time.Duration, error) {
@@ -113,6 +158,12 @@ func getTimeoutOptionValue(v string) (time.Duration, error) {
 
 type Driver struct {
 	Alloc memory.Allocator
+	// RequestMetadata, if set, is called before every RPC made by
+	// databases created from this driver, with the full gRPC method
+	// name. The returned metadata is sent along with the static
+	// headers. If it returns an error, the RPC is not made and the
+	// error is returned instead.
+	RequestMetadata func(ctx context.Context, method string) (metadata.MD, error)
 }
 
 func (d Driver) NewDatabase(opts map[string]string) (adbc.Database, error) {
@@ -126,7 +177,7 @@ func (d Driver) NewDatabase(opts map[string]string) (adbc.Database, error) {
 	}
 	delete(opts, adbc.OptionKeyURI)
 
-	db := &database{alloc: d.Alloc, hdrs: make(metadata.MD)}
+	db := &database{alloc: d.Alloc, hdrs: make(metadata.MD), requestMetadata: d.RequestMetadata}
 	if db.alloc == nil {
 		db.alloc = memory.DefaultAllocator
 	}
@@ -141,14 +192,26 @@ func (d Driver) NewDatabase(opts map[string]string) (adbc.Database, error) {
 	// Use WithMaxMsgSize(16 MiB) since Flight services tend to send large messages
 	db.dialOpts.block = false
 	db.dialOpts.maxMsgSize = 16 * 1024 * 1024
+	db.dialOpts.userAgent = defaultUserAgent()
 
 	return db, db.SetOptions(opts)
 }
 
 type dbDialOpts struct {
-	opts       []grpc.DialOption
-	block      bool
-	maxMsgSize int
+	opts          []grpc.DialOption
+	block         bool
+	maxMsgSize    int
+	serviceConfig string
+	userAgent     string
+}
+
+// defaultUserAgent identifies the driver to the server. gRPC appends
+// its own user agent to this.
+func defaultUserAgent() string {
+	if infoDriverVersion == "" {
+		return infoDriverName
+	}
+	return infoDriverName + " " + infoDriverVersion
 }
 
 func (d *dbDialOpts) rebuild() {
@@ -159,6 +222,12 @@ func (d *dbDialOpts) rebuild() {
 	if d.block {
 		d.opts = append(d.opts, grpc.WithBlock())
 	}
+	if d.serviceConfig != "" {
+		d.opts = append(d.opts, grpc.WithDefaultServiceConfig(d.serviceConfig))
+	}
+	if d.userAgent != "" {
+		d.opts = append(d.opts, grpc.WithUserAgent(d.userAgent))
+	}
 }
 
 type database struct {
@@ -169,6 +238,17 @@ type database struct {
 	timeout    timeoutOption
 	dialOpts   dbDialOpts
 
+	locationRewrites map[string]string
+	reuseConnection  bool
+	locationPref     locationPreference
+
+	shareChannel bool
+	channel      sharedChannel
+
+	maxConcurrentStreams int
+
+	requestMetadata func(ctx context.Context, method string) (metadata.MD, error)
+
 	alloc memory.Allocator
 }
 
@@ -322,8 +402,117 @@ func (d *database) SetOptions(cnOptions map[string]string) error {
 		d.dialOpts.maxMsgSize = size
 		delete(cnOptions, OptionWithMaxMsgSize)
 	}
+	if val, ok := cnOptions[OptionUserAgent]; ok {
+		if !isPrintableASCII(val) {
+			return adbc.Error{
+				Msg:  fmt.Sprintf("Invalid value for database option '%s': '%s' is not a non-empty, printable ASCII string", OptionUserAgent, val),
+				Code: adbc.StatusInvalidArgument,
+			}
+		}
+		d.dialOpts.userAgent = val
+		delete(cnOptions, OptionUserAgent)
+	}
+	if val, ok := cnOptions[OptionServiceConfig]; ok {
+		// gRPC only validates the config when dialing, so at least
+		// check that it is a JSON object here
+		var config map[string]interface{}
+		if err := json.Unmarshal([]byte(val), &config); err != nil {
+			return adbc.Error{
+				Msg:  fmt.Sprintf("Invalid value for database option '%s': %s", OptionServiceConfig, err.Error()),
+				Code: adbc.StatusInvalidArgument,
+			}
+		}
+		d.dialOpts.serviceConfig = val
+		delete(cnOptions, OptionServiceConfig)
+	}
 	d.dialOpts.rebuild()
 
+	if val, ok := cnOptions[OptionLocationRewrite]; ok {
+		rewrites := make(map[string]string)
+		for _, pair := range strings.Split(val, ",") {
+			from, to, found := strings.Cut(strings.TrimSpace(pair), "=")
+			if !found || from == "" || to == "" {
+				return adbc.Error{
+					Msg:  fmt.Sprintf("Invalid value for database option '%s': '%s' is not of the form host[:port]=replacement[:port]", OptionLocationRewrite, pair),
+					Code: adbc.StatusInvalidArgument,
+				}
+			}
+			rewrites[from] = to
+		}
+		d.locationRewrites = rewrites
+		delete(cnOptions, OptionLocationRewrite)
+	}
+
+	if val, ok := cnOptions[OptionLocationReuseConnection]; ok {
+		if val == adbc.OptionValueEnabled {
+			d.reuseConnection = true
+		} else if val == adbc.OptionValueDisabled {
+			d.reuseConnection = false
+		} else {
+			return adbc.Error{
+				Msg:  fmt.Sprintf("Invalid value for database option '%s': '%s'", OptionLocationReuseConnection, val),
+				Code: adbc.StatusInvalidArgument,
+			}
+		}
+		delete(cnOptions, OptionLocationReuseConnection)
+	}
+
+	if val, ok := cnOptions[OptionLocationSchemePreference]; ok {
+		var schemes []string
+		for _, scheme := range strings.Split(val, ",") {
+			scheme = strings.ToLower(strings.TrimSpace(scheme))
+			if scheme == "" {
+				return adbc.Error{
+					Msg:  fmt.Sprintf("Invalid value for database option '%s': '%s' contains an empty scheme", OptionLocationSchemePreference, val),
+					Code: adbc.StatusInvalidArgument,
+				}
+			}
+			schemes = append(schemes, scheme)
+		}
+		d.locationPref = locationPreference{schemes: schemes, resolve: d.resolveLocation}
+		delete(cnOptions, OptionLocationSchemePreference)
+	}
+
+	if val, ok := cnOptions[OptionMaxConcurrentStreams]; ok {
+		var err error
+		var limit int
+		if limit, err = strconv.Atoi(val); err != nil || limit <= 0 {
+			return adbc.Error{
+				Msg:  fmt.Sprintf("Invalid value for database option '%s': '%s' is not a positive integer", OptionMaxConcurrentStreams, val),
+				Code: adbc.StatusInvalidArgument,
+			}
+		}
+		d.maxConcurrentStreams = limit
+		delete(cnOptions, OptionMaxConcurrentStreams)
+	}
+
+	if val, ok := cnOptions[OptionChannelShared]; ok {
+		if val == adbc.OptionValueEnabled {
+			d.shareChannel = true
+		} else if val == adbc.OptionValueDisabled {
+			d.shareChannel = false
+		} else {
+			return adbc.Error{
+				Msg:  fmt.Sprintf("Invalid value for database option '%s': '%s'", OptionChannelShared, val),
+				Code: adbc.StatusInvalidArgument,
+			}
+		}
+		delete(cnOptions, OptionChannelShared)
+	}
+
+	if val, ok := cnOptions[OptionChannelIdleTimeout]; ok {
+		timeout, err := getTimeoutOptionValue(val)
+		if err != nil {
+			return adbc.Error{
+				Msg: fmt.Sprintf("invalid timeout option value %s = %s : %s",
+					OptionChannelIdleTimeout, val, err.Error()),
+				Code: adbc.StatusInvalidArgument,
+			}
+		}
+		d.channel.setIdleTimeout(timeout)
+		delete(cnOptions, OptionChannelIdleTimeout)
+	}
+
 	for key, val := range cnOptions {
 		if strings.HasPrefix(key, OptionRPCCallHeaderPrefix) {
 			d.hdrs.Append(strings.TrimPrefix(key, OptionRPCCallHeaderPrefix), val)
@@ -338,6 +527,78 @@ func (d *database) SetOptions(cnOptions map[string]string) error {
 	return nil
 }
 
+// resolveLocation maps a FlightEndpoint location returned by the server
+// to the location the driver should actually connect to.
+func (d *database) resolveLocation(loc string) string {
+	if d.reuseConnection {
+		return d.uri.String()
+	}
+
+	if len(d.locationRewrites) == 0 {
+		return loc
+	}
+
+	uri, err := url.Parse(loc)
+	if err != nil {
+		// let getFlightClient report the invalid URI
+		return loc
+	}
+
+	if host, ok := d.locationRewrites[uri.Host]; ok {
+		uri.Host = host
+		return uri.String()
+	}
+
+	// fall back to rules for the host alone, which apply to any port
+	if host, ok := d.locationRewrites[uri.Hostname()]; ok {
+		if _, _, err := net.SplitHostPort(host); err != nil && uri.Port() != "" {
+			host = net.JoinHostPort(strings.Trim(host, "[]"), uri.Port())
+		}
+		uri.Host = host
+		return uri.String()
+	}
+	return loc
+}
+
+// locationPreference is passed as a call option to doGet to filter and
+// order the locations of a FlightEndpoint by their scheme. The scheme
+// checked is that of the location the driver actually connects to, as
+// given by resolve, which may differ from the one the server returned.
+type locationPreference struct {
+	grpc.EmptyCallOption
+
+	schemes []string
+	resolve func(loc string) string
+}
+
+func getLocationPreference(callOptions []grpc.CallOption) (locationPreference, bool) {
+	for _, opt := range callOptions {
+		if pref, ok := opt.(locationPreference); ok && len(pref.schemes) > 0 {
+			return pref, true
+		}
+	}
+	return locationPreference{}, false
+}
+
+// order returns the locations whose scheme is preferred, most preferred
+// first. Locations with the same scheme keep their original order.
+func (p locationPreference) order(locations []*flight.Location) []*flight.Location {
+	out := make([]*flight.Location, 0, len(locations))
+	for _, scheme := range p.schemes {
+		for _, loc := range locations {
+			resolved := loc.Uri
+			if p.resolve != nil {
+				resolved = p.resolve(resolved)
+			}
+			uri, err := url.Parse(resolved)
+			if err == nil && uri.Scheme == scheme {
+				out = append(out, loc)
+			}
+		}
+	}
+	return out
+}
+
 type timeoutOption struct {
 	grpc.EmptyCallOption
 
@@ -389,6 +650,130 @@ func streamTimeoutInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *gr
 	return streamer(ctx, desc, cc, method, opts...)
 }
 
+func withRequestMetadata(ctx context.Context, method string, fn func(context.Context, string) (metadata.MD, error)) (context.Context, error) {
+	md, err := fn(ctx, method)
+	if err != nil {
+		return ctx, err
+	}
+	existing, _ := metadata.FromOutgoingContext(ctx)
+	return metadata.NewOutgoingContext(ctx, metadata.Join(existing, md)), nil
+}
+
+func unaryMetadataInterceptor(fn func(context.Context, string) (metadata.MD, error)) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, err := withRequestMetadata(ctx, method, fn)
+		if err != nil {
+			return err
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func streamMetadataInterceptor(fn func(context.Context, string) (metadata.MD, error)) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, err := withRequestMetadata(ctx, method, fn)
+		if err != nil {
+			return nil, err
+		}
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+// streamLimiter bounds the number of DoGet streams open at the same
+// time on a single channel. Further streams wait until a slot is free
+// or their context is done.
+type streamLimiter struct {
+	sem *semaphore.Weighted
+}
+
+func newStreamLimiter(limit int) *streamLimiter {
+	return &streamLimiter{sem: semaphore.NewWeighted(int64(limit))}
+}
+
+func (l *streamLimiter) interceptStream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	if !strings.HasSuffix(method, "DoGet") {
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+
+	if order, ok := ctx.Value(streamOrderKey{}).(*streamOrder); ok {
+		defer order.granted()
+		if order.prev != nil {
+			select {
+			case <-order.prev:
+			case <-ctx.Done():
+				return nil, status.FromContextError(ctx.Err()).Err()
+			}
+		}
+	}
+
+	if err := l.sem.Acquire(ctx, 1); err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		l.sem.Release(1)
+		return nil, err
+	}
+
+	limited := &limitedStream{ClientStream: stream, done: make(chan struct{})}
+	// a stream ends when it is fully read or its context is done
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-limited.done:
+		}
+		l.sem.Release(1)
+	}()
+	return limited, nil
+}
+
+// streamOrder makes the DoGet calls for the partitions of a result set
+// wait for a slot in partition order: a partition only starts waiting
+// once the previous one was granted its slot, and the semaphore grants
+// slots first come, first served. Otherwise a later partition could take
+// the last slot, fill its queue and block before reaching the end of its
+// stream, while the partition being read waits for that slot forever.
+type streamOrder struct {
+	prev <-chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+type streamOrderKey struct{}
+
+// withStreamOrder returns a context for the DoGet call of the partition
+// following prev (nil for the first partition).
+func withStreamOrder(ctx context.Context, prev *streamOrder) (context.Context, *streamOrder) {
+	order := &streamOrder{done: make(chan struct{})}
+	if prev != nil {
+		order.prev = prev.done
+	}
+	return context.WithValue(ctx, streamOrderKey{}, order), order
+}
+
+// granted lets the next partition wait for its slot. It must also be
+// called if this partition never waits for one, e.g. because its DoGet
+// call failed early or no limit is set.
+func (o *streamOrder) granted() {
+	o.once.Do(func() { close(o.done) })
+}
+
+type limitedStream struct {
+	grpc.ClientStream
+
+	once sync.Once
+	done chan struct{}
+}
+
+func (s *limitedStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.once.Do(func() { close(s.done) })
+	}
+	return err
+}
+
 type bearerAuthMiddleware struct {
 	hdrs metadata.MD
 }
@@ -398,6 +783,27 @@ func (b *bearerAuthMiddleware) StartCall(ctx context.Context) context.Context {
 	return metadata.NewOutgoingContext(ctx, metadata.Join(md, b.hdrs))
 }
 
+func getDialOptions(uri *url.URL, d *database) []grpc.DialOption {
+	creds := d.creds
+	if uri.Scheme == "grpc" || uri.Scheme == "grpc+tcp" {
+		creds = insecure.NewCredentials()
+	}
+
+	dialOpts := make([]grpc.DialOption, 0, len(d.dialOpts.opts)+2)
+	dialOpts = append(dialOpts, d.dialOpts.opts...)
+	if d.requestMetadata != nil {
+		dialOpts = append(dialOpts,
+			grpc.WithChainUnaryInterceptor(unaryMetadataInterceptor(d.requestMetadata)),
+			grpc.WithChainStreamInterceptor(streamMetadataInterceptor(d.requestMetadata)))
+	}
+	if d.maxConcurrentStreams > 0 {
+		// each dial creates a new channel, so each gets its own limit
+		limiter := newStreamLimiter(d.maxConcurrentStreams)
+		dialOpts = append(dialOpts, grpc.WithChainStreamInterceptor(limiter.interceptStream))
+	}
+	return append(dialOpts, grpc.WithTransportCredentials(creds))
+}
+
 func getFlightClient(ctx context.Context, loc string, d *database) (*flightsql.Client, error) {
 	authMiddle := &bearerAuthMiddleware{hdrs: d.hdrs.Copy()}
 	middleware := []flight.ClientMiddleware{
@@ -412,13 +818,8 @@ func getFlightClient(ctx context.Context, loc string, d *database) (*flightsql.C
 	if err != nil {
 		return nil, adbc.Error{Msg: fmt.Sprintf("Invalid URI '%s': %s", loc, err), Code: adbc.StatusInvalidArgument}
 	}
-	creds := d.creds
-	if uri.Scheme == "grpc" || uri.Scheme == "grpc+tcp" {
-		creds = insecure.NewCredentials()
-	}
-	dialOpts := append(d.dialOpts.opts, grpc.WithTransportCredentials(creds))
 
-	cl, err := flightsql.NewClient(uri.Host, nil, middleware, dialOpts...)
+	cl, err := flightsql.NewClient(uri.Host, nil, middleware, getDialOptions(uri, d)...)
 	if err != nil {
 		return nil, adbc.Error{
 			Msg:  err.Error(),
@@ -427,21 +828,164 @@ func getFlightClient(ctx context.Context, loc string, d *database) (*flightsql.C
 	}
 
 	cl.Alloc = d.alloc
-	if d.user != "" || d.pass != "" {
-		ctx, err = cl.Client.AuthenticateBasicToken(ctx, d.user, d.pass)
+	if err := authenticate(ctx, cl, authMiddle, d); err != nil {
+		return nil, err
+	}
+
+	return cl, nil
+}
+
+// getSharedFlightClient returns a client for the database's URI which
+// uses the database's shared channel. Headers (including authorization)
+// are still tracked per client.
+func getSharedFlightClient(ctx context.Context, d *database) (*flightsql.Client, error) {
+	conn, err := d.channel.acquire(d)
+	if err != nil {
+		return nil, err
+	}
+
+	authMiddle := &bearerAuthMiddleware{hdrs: d.hdrs.Copy()}
+	cc := &sharedChannelConn{channel: &d.channel, conn: conn, auth: authMiddle}
+	cl := &flightsql.Client{Client: flight.NewClientFromConn(cc, nil), Alloc: d.alloc}
+	if err := authenticate(ctx, cl, authMiddle, d); err != nil {
+		cl.Close()
+		return nil, err
+	}
+
+	return cl, nil
+}
+
+func authenticate(ctx context.Context, cl *flightsql.Client, authMiddle *bearerAuthMiddleware, d *database) error {
+	if d.user == "" && d.pass == "" {
+		return nil
+	}
+
+	ctx, err := cl.Client.AuthenticateBasicToken(ctx, d.user, d.pass)
+	if err != nil {
+		return adbc.Error{
+			Msg:  err.Error(),
+			Code: adbc.StatusUnauthenticated,
+		}
+	}
+
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		authMiddle.hdrs.Set("authorization", md.Get("Authorization")[0])
+	}
+	return nil
+}
+
+// sharedChannel is a gRPC channel to the database's URI which can be
+// used by several connections at once. The channel is dialed by the
+// first connection to use it and closed once the last one is closed,
+// or, if an idle timeout is set, once it has not been used for that
+// long. It is dialed again by the next connection.
+type sharedChannel struct {
+	mu   sync.Mutex
+	conn *grpc.ClientConn
+	refs int
+
+	idleTimeout time.Duration
+	idle        *time.Timer
+	// idleGen identifies the current idle timer, so that a timer which
+	// fires after the channel was acquired again does nothing
+	idleGen uint64
+}
+
+func (s *sharedChannel) setIdleTimeout(timeout time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.idleTimeout = timeout
+}
+
+func (s *sharedChannel) acquire(d *database) (*grpc.ClientConn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.idle != nil {
+		s.idle.Stop()
+		s.idle = nil
+		s.idleGen++
+	}
+
+	if s.conn == nil {
+		dialOpts := append(getDialOptions(d.uri, d),
+			grpc.WithChainUnaryInterceptor(unaryTimeoutInterceptor),
+			grpc.WithChainStreamInterceptor(streamTimeoutInterceptor))
+		conn, err := grpc.Dial(d.uri.Host, dialOpts...)
 		if err != nil {
 			return nil, adbc.Error{
 				Msg:  err.Error(),
-				Code: adbc.StatusUnauthenticated,
+				Code: adbc.StatusIO,
 			}
 		}
+		s.conn = conn
+	}
 
-		if md, ok := metadata.FromOutgoingContext(ctx); ok {
-			authMiddle.hdrs.Set("authorization", md.Get("Authorization")[0])
-		}
+	s.refs++
+	return s.conn, nil
+}
+
+func (s *sharedChannel) release() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.refs--
+	if s.refs > 0 {
+		return nil
 	}
 
-	return cl, nil
+	if s.idleTimeout > 0 {
+		s.idleGen++
+		gen := s.idleGen
+		s.idle = time.AfterFunc(s.idleTimeout, func() { s.reap(gen) })
+		return nil
+	}
+
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// reap closes the channel if it has stayed unused since the idle timer
+// identified by gen was started.
+func (s *sharedChannel) reap(gen uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if gen != s.idleGen || s.refs > 0 || s.conn == nil {
+		return
+	}
+
+	// nobody is left to report the error to
+	_ = s.conn.Close()
+	s.conn = nil
+	s.idle = nil
+}
+
+// sharedChannelConn is a single connection's view of a sharedChannel.
+// It attaches the connection's headers to every call, and releases
+// the channel instead of closing it.
+type sharedChannelConn struct {
+	channel *sharedChannel
+	conn    *grpc.ClientConn
+	auth    *bearerAuthMiddleware
+	closed  bool
+}
+
+func (s *sharedChannelConn) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	return s.conn.Invoke(s.auth.StartCall(ctx), method, args, reply, opts...)
+}
+
+func (s *sharedChannelConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return s.conn.NewStream(s.auth.StartCall(ctx), desc, method, opts...)
+}
+
+func (s *sharedChannelConn) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.channel.release()
 }
 
 type support struct {
@@ -449,7 +993,15 @@ type support struct {
 }
 
 func (d *database) Open(ctx context.Context) (adbc.Connection, error) {
-	cl, err := getFlightClient(ctx, d.uri.String(), d)
+	var (
+		cl  *flightsql.Client
+		err error
+	)
+	if d.shareChannel {
+		cl, err = getSharedFlightClient(ctx, d)
+	} else {
+		cl, err = getFlightClient(ctx, d.uri.String(), d)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -462,7 +1014,7 @@ func (d *database) Open(ctx context.Context) (adbc.Connection, error) {
 				return nil, adbc.Error{Msg: fmt.Sprintf("Location must be a string, got %#v", uri), Code: adbc.StatusInternal}
 			}
 
-			cl, err := getFlightClient(context.Background(), uri, d)
+			cl, err := getFlightClient(context.Background(), d.resolveLocation(uri), d)
 			if err != nil {
 				return nil, err
 			}
@@ -483,7 +1035,7 @@ func (d *database) Open(ctx context.Context) (adbc.Connection, error) {
 		const int32code = 3
 
 		for _, endpoint := range info.Endpoint {
-			rdr, err := doGet(ctx, cl, endpoint, cache, d.timeout)
+			rdr, err := doGet(ctx, cl, endpoint, cache, d.timeout, d.locationPref)
 			if err != nil {
 				continue
 			}
@@ -531,6 +1083,9 @@ type cnxn struct {
 	timeouts    timeoutOption
 	txn         *flightsql.Txn
 	supportInfo support
+
+	prepareMu sync.Mutex
+	preparing map[string]*preparingCall
 }
 
 var adbcToFlightSQLInfo = map[adbc.InfoCode]flightsql.SqlInfo{
@@ -539,16 +1094,37 @@ var adbcToFlightSQLInfo = map[adbc.InfoCode]flightsql.SqlInfo{
 	adbc.InfoVendorArrowVersion: flightsql.SqlInfoFlightSqlServerArrowVersion,
 }
 
-func doGet(ctx context.Context, cl *flightsql.Client, endpoint *flight.FlightEndpoint, clientCache gcache.Cache, opts ...grpc.CallOption) (rdr *flight.Reader, err error) {
+func doGet(ctx context.Context, cl *flightsql.Client, endpoint *flight.FlightEndpoint, clientCache gcache.Cache, opts ...grpc.CallOption) (*doGetReader, error) {
+	ctx, cancelFn := context.WithCancel(ctx)
+	rdr, err := doGetLocation(ctx, cl, endpoint, clientCache, opts...)
+	if err != nil {
+		cancelFn()
+		return nil, err
+	}
+	return &doGetReader{Reader: rdr, refCount: 1, cancelFn: cancelFn}, nil
+}
+
+func doGetLocation(ctx context.Context, cl *flightsql.Client, endpoint *flight.FlightEndpoint, clientCache gcache.Cache, opts ...grpc.CallOption) (rdr *flight.Reader, err error) {
 	if len(endpoint.Location) == 0 {
 		return cl.DoGet(ctx, endpoint.Ticket, opts...)
 	}
 
+	locations := endpoint.Location
+	if pref, ok := getLocationPreference(opts); ok {
+		locations = pref.order(locations)
+		if len(locations) == 0 {
+			return nil, adbc.Error{
+				Msg:  fmt.Sprintf("[Flight SQL] no location of the endpoint uses a scheme allowed by %s (%s)", OptionLocationSchemePreference, strings.Join(pref.schemes, ",")),
+				Code: adbc.StatusIO,
+			}
+		}
+	}
+
 	var (
 		cc interface{}
 	)
 
-	for _, loc := range endpoint.Location {
+	for _, loc := range locations {
 		cc, err = clientCache.Get(loc.Uri)
 		if err != nil {
 			continue
@@ -726,6 +1302,13 @@ func (c *cnxn) GetInfo(ctx context.Context, infoCodes []adbc.InfoCode) (array.Re
 		}
 	}
 
+	if len(translated) == 0 {
+		// an empty request would make the server return all of its info
+		final := bldr.NewRecord()
+		defer final.Release()
+		return array.NewRecordReader(adbc.GetInfoSchema, []arrow.Record{final})
+	}
+
 	ctx = metadata.NewOutgoingContext(ctx, c.hdrs)
 	info, err := c.cl.GetSqlInfo(ctx, translated, c.timeouts)
 	if err != nil {
@@ -733,10 +1316,11 @@ func (c *cnxn) GetInfo(ctx context.Context, infoCodes []adbc.InfoCode) (array.Re
 	}
 
 	for _, endpoint := range info.Endpoint {
-		rdr, err := doGet(ctx, c.cl, endpoint, c.clientCache, c.timeouts)
+		rdr, err := doGet(ctx, c.cl, endpoint, c.clientCache, c.timeouts, c.db.locationPref)
 		if err != nil {
 			return nil, adbcFromFlightStatus(err)
 		}
+		defer rdr.Release()
 
 		for rdr.Next() {
 			rec := rdr.Record()
@@ -914,6 +1498,7 @@ type getObjects struct {
 	builder           *array.RecordBuilder
 	schemaLookup      map[string][]string
 	tableLookup       map[catalogAndSchema][]tableInfo
+	typeInfoLookup    map[string]xdbcTypeInfo
 	catalogPattern    *regexp.Regexp
 	columnNamePattern *regexp.Regexp
 
@@ -962,6 +1547,11 @@ func (g *getObjects) init(c *cnxn) error {
 		g.tableLookup = tableLookup
 	}
 
+	if g.depth == adbc.ObjectDepthColumns {
+		// XDBC type info is optional, so don't fail if the server doesn't support it
+		g.typeInfoLookup, _ = c.getXdbcTypeInfo(g.ctx)
+	}
+
 	if catalogPattern, err := patternToRegexp(g.catalog); err != nil {
 		return adbc.Error{
 			Msg:  err.Error(),
@@ -1087,31 +1677,102 @@ func (g *getObjects) appendTableInfo(tableInfo tableInfo) {
 		g.columnNameBuilder.Append(column.Name)
 		g.ordinalPositionBuilder.Append(int32(colIndex + 1))
 		g.remarksBuilder.AppendNull()
-		g.xdbcDataTypeBuilder.AppendNull()
-		g.xdbcTypeNameBuilder.AppendNull()
-		g.xdbcColumnSizeBuilder.AppendNull()
-		g.xdbcDecimalDigitsBuilder.AppendNull()
-		g.xdbcNumPrecRadixBuilder.AppendNull()
-		g.xdbcNullableBuilder.AppendNull()
+
+		typeName, hasTypeName := getMetadataValue(column.Metadata, flightsql.TypeNameKey)
+		typeInfo, hasTypeInfo := g.typeInfoLookup[typeName]
+		if hasTypeInfo {
+			g.xdbcDataTypeBuilder.Append(typeInfo.dataType)
+		} else {
+			g.xdbcDataTypeBuilder.AppendNull()
+		}
+		if hasTypeName {
+			g.xdbcTypeNameBuilder.Append(typeName)
+		} else {
+			g.xdbcTypeNameBuilder.AppendNull()
+		}
+		appendInt32Metadata(g.xdbcColumnSizeBuilder, column.Metadata, flightsql.PrecisionKey)
+		if scale, ok := getInt32Metadata(column.Metadata, flightsql.ScaleKey); ok {
+			g.xdbcDecimalDigitsBuilder.Append(int16(scale))
+		} else {
+			g.xdbcDecimalDigitsBuilder.AppendNull()
+		}
+		if hasTypeInfo && typeInfo.numPrecRadix != nil {
+			g.xdbcNumPrecRadixBuilder.Append(*typeInfo.numPrecRadix)
+		} else {
+			g.xdbcNumPrecRadixBuilder.AppendNull()
+		}
+		if column.Nullable {
+			g.xdbcNullableBuilder.Append(xdbcColumnNullable)
+			g.xdbcIsNullableBuilder.Append("YES")
+		} else {
+			g.xdbcNullableBuilder.Append(xdbcColumnNoNulls)
+			g.xdbcIsNullableBuilder.Append("NO")
+		}
+		// Flight SQL has no column metadata key for default values
 		g.xdbcColumnDefBuilder.AppendNull()
-		g.xdbcSqlDataTypeBuilder.AppendNull()
-		g.xdbcDatetimeSubBuilder.AppendNull()
+		if hasTypeInfo {
+			g.xdbcSqlDataTypeBuilder.Append(typeInfo.sqlDataType)
+		} else {
+			g.xdbcSqlDataTypeBuilder.AppendNull()
+		}
+		if hasTypeInfo && typeInfo.datetimeSub != nil {
+			g.xdbcDatetimeSubBuilder.Append(*typeInfo.datetimeSub)
+		} else {
+			g.xdbcDatetimeSubBuilder.AppendNull()
+		}
 		g.xdbcCharOctetLengthBuilder.AppendNull()
-		g.xdbcIsNullableBuilder.AppendNull()
 		g.xdbcScopeCatalogBuilder.AppendNull()
 		g.xdbcScopeSchemaBuilder.AppendNull()
 		g.xdbcScopeTableBuilder.AppendNull()
-		g.xdbcIsAutoincrementBuilder.AppendNull()
+		if v, ok := getMetadataValue(column.Metadata, flightsql.IsAutoIncrementKey); ok {
+			g.xdbcIsAutoincrementBuilder.Append(v == "1")
+		} else {
+			g.xdbcIsAutoincrementBuilder.AppendNull()
+		}
 		g.xdbcIsGeneratedcolumnBuilder.AppendNull()
 
 		g.tableColumnsItems.Append(true)
 	}
 }
 
+// Values for xdbc_nullable, matching SQL_NO_NULLS and SQL_NULLABLE in ODBC
+const (
+	xdbcColumnNoNulls  int16 = 0
+	xdbcColumnNullable int16 = 1
+)
+
+func getMetadataValue(md arrow.Metadata, key string) (string, bool) {
+	idx := md.FindKey(key)
+	if idx < 0 {
+		return "", false
+	}
+	return md.Values()[idx], true
+}
+
+func getInt32Metadata(md arrow.Metadata, key string) (int32, bool) {
+	v, ok := getMetadataValue(md, key)
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(v, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return int32(n), true
+}
+
+func appendInt32Metadata(bldr *array.Int32Builder, md arrow.Metadata, key string) {
+	if v, ok := getInt32Metadata(md, key); ok {
+		bldr.Append(v)
+	} else {
+		bldr.AppendNull()
+	}
+}
+
 // Helper function to read and validate a metadata stream
 func (c *cnxn) readInfo(ctx context.Context, expectedSchema *arrow.Schema, info *flight.FlightInfo) (array.RecordReader, error) {
 	// use a default queueSize for the reader
-	rdr, err := newRecordReader(ctx, c.db.alloc, c.cl, info, c.clientCache, 5)
+	rdr, err := newRecordReader(ctx, c.db.alloc, c.cl, info, c.clientCache, 5, c.db.locationPref)
 	if err != nil {
 		return nil, adbcFromFlightStatus(err)
 	}
@@ -1284,6 +1945,65 @@ func (c *cnxn) getObjectsTables(ctx context.Context, depth adbc.ObjectDepth, cat
 	return
 }
 
+type xdbcTypeInfo struct {
+	dataType     int16
+	sqlDataType  int16
+	datetimeSub  *int16
+	numPrecRadix *int16
+}
+
+// Helper function to build up a map of type names to XDBC type info
+func (c *cnxn) getXdbcTypeInfo(ctx context.Context) (result map[string]xdbcTypeInfo, err error) {
+	info, err := c.cl.GetXdbcTypeInfo(ctx, nil, c.timeouts)
+	if err != nil {
+		return nil, adbcFromFlightStatus(err)
+	}
+
+	rdr, err := c.readInfo(ctx, schema_ref.XdbcTypeInfo, info)
+	if err != nil {
+		return nil, adbcFromFlightStatus(err)
+	}
+	defer rdr.Release()
+
+	optionalInt16 := func(arr *array.Int32, i int) *int16 {
+		if arr.IsNull(i) {
+			return nil
+		}
+		v := int16(arr.Value(i))
+		return &v
+	}
+
+	result = make(map[string]xdbcTypeInfo)
+	for rdr.Next() {
+		rec := rdr.Record()
+		typeName := rec.Column(0).(*array.String)
+		dataType := rec.Column(1).(*array.Int32)
+		sqlDataType := rec.Column(15).(*array.Int32)
+		datetimeSub := rec.Column(16).(*array.Int32)
+		numPrecRadix := rec.Column(17).(*array.Int32)
+
+		for i := 0; i < typeName.Len(); i++ {
+			name := string([]byte(typeName.Value(i)))
+			// Keep the first entry if a server reports a type name twice
+			if _, ok := result[name]; ok {
+				continue
+			}
+			result[name] = xdbcTypeInfo{
+				dataType:     int16(dataType.Value(i)),
+				sqlDataType:  int16(sqlDataType.Value(i)),
+				datetimeSub:  optionalInt16(datetimeSub, i),
+				numPrecRadix: optionalInt16(numPrecRadix, i),
+			}
+		}
+	}
+
+	if rdr.Err() != nil {
+		result = nil
+		err = adbcFromFlightStatus(rdr.Err())
+	}
+	return
+}
+
 func (c *cnxn) GetTableSchema(ctx context.Context, catalog *string, dbSchema *string, tableName string) (*arrow.Schema, error) {
 	opts := &flightsql.GetTablesOpts{
 		Catalog:                catalog,
@@ -1298,7 +2018,7 @@ func (c *cnxn) GetTableSchema(ctx context.Context, catalog *string, dbSchema *st
 		return nil, adbcFromFlightStatus(err)
 	}
 
-	rdr, err := doGet(ctx, c.cl, info.Endpoint[0], c.clientCache, c.timeouts)
+	rdr, err := doGet(ctx, c.cl, info.Endpoint[0], c.clientCache, c.timeouts, c.db.locationPref)
 	if err != nil {
 		return nil, adbcFromFlightStatus(err)
 	}
@@ -1350,7 +2070,81 @@ func (c *cnxn) GetTableTypes(ctx context.Context) (array.RecordReader, error) {
 		return nil, adbcFromFlightStatus(err)
 	}
 
-	return newRecordReader(ctx, c.db.alloc, c.cl, info, c.clientCache, 5)
+	return newRecordReader(ctx, c.db.alloc, c.cl, info, c.clientCache, 5, c.db.locationPref)
+}
+
+// Connection is implemented by connections opened by this driver and
+// exposes Flight SQL functionality that is not part of the generic ADBC
+// API. Callers can access it with a type assertion on an adbc.Connection.
+type Connection interface {
+	adbc.Connection
+
+	// GetCrossReference returns the foreign keys of the table fkTable
+	// that reference the primary key of the table pkTable. A nil
+	// catalog or dbSchema matches tables without regard to that field.
+	//
+	// The result is an Arrow dataset with the Flight SQL cross-reference
+	// schema (pk_catalog_name, pk_db_schema_name, pk_table_name,
+	// pk_column_name, fk_catalog_name, fk_db_schema_name, fk_table_name,
+	// fk_column_name, key_sequence, fk_key_name, pk_key_name,
+	// update_rule, delete_rule). If the server does not support the
+	// request, an error with StatusNotImplemented is returned.
+	GetCrossReference(ctx context.Context, pkCatalog, pkDbSchema *string, pkTable string, fkCatalog, fkDbSchema *string, fkTable string) (array.RecordReader, error)
+
+	// DoExchange performs a Flight DoExchange call with the given
+	// descriptor, streaming the records of input to the server and
+	// returning the records the server sends back. This is intended for
+	// servers that expose custom operators and is not part of Flight SQL.
+	//
+	// The driver will call Release on input once it has been sent.
+	DoExchange(ctx context.Context, desc *flight.FlightDescriptor, input array.RecordReader) (array.RecordReader, error)
+}
+
+func (c *cnxn) GetCrossReference(ctx context.Context, pkCatalog, pkDbSchema *string, pkTable string, fkCatalog, fkDbSchema *string, fkTable string) (array.RecordReader, error) {
+	pkRef := flightsql.TableRef{Catalog: pkCatalog, DBSchema: pkDbSchema, Table: pkTable}
+	fkRef := flightsql.TableRef{Catalog: fkCatalog, DBSchema: fkDbSchema, Table: fkTable}
+
+	ctx = metadata.NewOutgoingContext(ctx, c.hdrs)
+	info, err := c.cl.GetCrossReference(ctx, pkRef, fkRef, c.timeouts)
+	if err != nil {
+		return nil, adbcFromFlightStatus(err)
+	}
+
+	return newRecordReader(ctx, c.db.alloc, c.cl, info, c.clientCache, 5, c.db.locationPref)
+}
+
+func (c *cnxn) DoExchange(ctx context.Context, desc *flight.FlightDescriptor, input array.RecordReader) (array.RecordReader, error) {
+	ctx = metadata.NewOutgoingContext(ctx, c.hdrs)
+	ctx, cancelFn := context.WithCancel(ctx)
+	stream, err := c.cl.Client.DoExchange(ctx, c.timeouts)
+	if err != nil {
+		cancelFn()
+		input.Release()
+		return nil, adbcFromFlightStatus(err)
+	}
+
+	// write from a separate goroutine so that servers which respond
+	// while still reading do not block on flow control
+	writeErr := make(chan error, 1)
+	go func() {
+		defer input.Release()
+		err := writeExchange(stream, desc, input)
+		if err != nil {
+			cancelFn()
+		}
+		writeErr <- err
+	}()
+
+	rdr, err := flight.NewRecordReader(stream, ipc.WithAllocator(c.db.alloc))
+	if err != nil {
+		cancelFn()
+		if werr := <-writeErr; werr != nil {
+			return nil, adbcFromFlightStatus(werr)
+		}
+		return nil, adbcFromFlightStatus(err)
+	}
+
+	return &exchangeReader{Reader: rdr, refCount: 1, cancelFn: cancelFn, writeErr: writeErr}, nil
 }
 
 // Commit commits any pending transactions on this connection, it should
@@ -1424,6 +2218,9 @@ func (c *cnxn) NewStatement() (adbc.Statement, error) {
 		queueSize:   5,
 		timeouts:    c.timeouts,
 		cnxn:        c,
+
+		totalRecords: -1,
+		totalBytes:   -1,
 	}, nil
 }
 
@@ -1467,6 +2264,157 @@ func (c *cnxn) prepare(ctx context.Context, query string, opts ...grpc.CallOptio
 	return c.cl.Prepare(ctx, query, opts...)
 }
 
+// sharedPrepared is a prepared statement handle which may be shared by
+// several statements of a connection that prepared the same query at
+// the same time. The handle is closed on the server once the last of
+// these statements releases it.
+type sharedPrepared struct {
+	*flightsql.PreparedStatement
+
+	mu   sync.Mutex
+	refs int
+}
+
+func newPrepared(prep *flightsql.PreparedStatement) *sharedPrepared {
+	return &sharedPrepared{PreparedStatement: prep, refs: 1}
+}
+
+// exclusive reports whether no other statement uses this handle.
+func (p *sharedPrepared) exclusive() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.refs == 1
+}
+
+func (p *sharedPrepared) release(ctx context.Context, opts ...grpc.CallOption) error {
+	p.mu.Lock()
+	p.refs--
+	last := p.refs == 0
+	p.mu.Unlock()
+
+	if !last {
+		return nil
+	}
+	return p.Close(ctx, opts...)
+}
+
+// preparingCall is a CreatePreparedStatement request in flight, which
+// other statements preparing the same query can wait on instead of
+// sending their own. refs counts the statements waiting on it; it and
+// finished are guarded by cnxn.prepareMu.
+type preparingCall struct {
+	done     chan struct{}
+	refs     int
+	finished bool
+	prep     *sharedPrepared
+	err      error
+}
+
+// prepareKey identifies identical prepare requests: the same query
+// sent with the same headers and timeouts.
+func prepareKey(ctx context.Context, query string, opts []grpc.CallOption) string {
+	var b strings.Builder
+	b.WriteString(strconv.Quote(query))
+	md, _ := metadata.FromOutgoingContext(ctx)
+	keys := maps.Keys(md)
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range md[k] {
+			b.WriteString(" " + strconv.Quote(k) + "=" + strconv.Quote(v))
+		}
+	}
+	for _, opt := range opts {
+		if t, ok := opt.(timeoutOption); ok {
+			fmt.Fprintf(&b, " timeouts=%d/%d/%d", t.fetchTimeout, t.queryTimeout, t.updateTimeout)
+		}
+	}
+	return b.String()
+}
+
+// isContextError reports whether err is the result of a context being
+// cancelled or reaching its deadline.
+func isContextError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	code := status.Code(err)
+	return code == codes.Canceled || code == codes.DeadlineExceeded
+}
+
+// prepareShared prepares a query, joining any identical prepare request
+// already in flight on this connection so that concurrent callers share
+// a single round trip and handle. Each caller holds one reference to the
+// returned handle.
+//
+// A caller that joins a request stops waiting when its own context is
+// done. If the joined request fails because the context of the caller
+// that sent it ended, the other callers try again instead of failing
+// with that error.
+func (c *cnxn) prepareShared(ctx context.Context, query string, opts ...grpc.CallOption) (*sharedPrepared, error) {
+	key := prepareKey(ctx, query, opts)
+
+	for {
+		c.prepareMu.Lock()
+		call, ok := c.preparing[key]
+		if !ok {
+			break
+		}
+		call.refs++
+		c.prepareMu.Unlock()
+
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			c.abandonPrepare(call, opts...)
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+
+		if call.err != nil && isContextError(call.err) && ctx.Err() == nil {
+			continue
+		}
+		return call.prep, call.err
+	}
+
+	// c.prepareMu is still held: this caller sends the request
+	if c.preparing == nil {
+		c.preparing = make(map[string]*preparingCall)
+	}
+	call := &preparingCall{done: make(chan struct{}), refs: 1}
+	c.preparing[key] = call
+	c.prepareMu.Unlock()
+
+	prep, err := c.prepare(ctx, query, opts...)
+
+	c.prepareMu.Lock()
+	delete(c.preparing, key)
+	if err == nil {
+		call.prep = &sharedPrepared{PreparedStatement: prep, refs: call.refs}
+	}
+	call.err = err
+	call.finished = true
+	c.prepareMu.Unlock()
+	close(call.done)
+
+	return call.prep, call.err
+}
+
+// abandonPrepare drops the reference of a caller which stopped waiting
+// for call.
+func (c *cnxn) abandonPrepare(call *preparingCall, opts ...grpc.CallOption) {
+	c.prepareMu.Lock()
+	if !call.finished {
+		call.refs--
+		c.prepareMu.Unlock()
+		return
+	}
+	c.prepareMu.Unlock()
+
+	if call.prep != nil {
+		// nobody is left to report the error to
+		_ = call.prep.release(context.Background(), opts...)
+	}
+}
+
 func (c *cnxn) prepareSubstrait(ctx context.Context, plan flightsql.SubstraitPlan, opts ...grpc.CallOption) (*flightsql.PreparedStatement, error) {
 	if c.txn != nil {
 		return c.txn.PrepareSubstrait(ctx, plan, opts...)
@@ -1511,7 +2459,7 @@ func (c *cnxn) ReadPartition(ctx context.Context, serializedPartition []byte) (r
 	}
 
 	ctx = metadata.NewOutgoingContext(ctx, c.hdrs)
-	rdr, err = doGet(ctx, c.cl, info.Endpoint[0], c.clientCache, c.timeouts)
+	rdr, err = doGet(ctx, c.cl, info.Endpoint[0], c.clientCache, c.timeouts, c.db.locationPref)
 	if err != nil {
 		return nil, adbcFromFlightStatus(err)
 	}
@@ -1520,4 +2468,6 @@ func (c *cnxn) ReadPartition(ctx context.Context, serializedPartition []byte) (r
 
 var (
 	_ adbc.PostInitOptions = (*cnxn)(nil)
+	_ Connection           = (*cnxn)(nil)
+	_ Statement            = (*statement)(nil)
 )
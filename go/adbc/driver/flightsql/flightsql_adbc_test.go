@@ -32,7 +32,10 @@ import (
 	"math/big"
 	"net"
 	"os"
+	"runtime/debug"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -44,12 +47,16 @@ import (
 	"github.com/apache/arrow/go/v12/arrow/flight"
 	"github.com/apache/arrow/go/v12/arrow/flight/flightsql"
 	"github.com/apache/arrow/go/v12/arrow/flight/flightsql/example"
+	"github.com/apache/arrow/go/v12/arrow/flight/flightsql/schema_ref"
+	"github.com/apache/arrow/go/v12/arrow/ipc"
 	"github.com/apache/arrow/go/v12/arrow/memory"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -253,8 +260,20 @@ func TestADBCFlightSQL(t *testing.T) {
 	suite.Run(t, &HeaderTests{Quirks: q})
 	suite.Run(t, &OptionTests{Quirks: q})
 	suite.Run(t, &PartitionTests{Quirks: q})
+	suite.Run(t, &InfoTests{Quirks: q})
 	suite.Run(t, &StatementTests{Quirks: q})
 	suite.Run(t, &TimeoutTestSuite{})
+	suite.Run(t, &GetObjectsTestSuite{})
+	suite.Run(t, &LocationTestSuite{})
+	suite.Run(t, &SharedChannelTestSuite{})
+	suite.Run(t, &CrossReferenceTestSuite{})
+	suite.Run(t, &ExchangeTestSuite{})
+	suite.Run(t, &ResultSizeTestSuite{})
+	suite.Run(t, &StreamLimitTestSuite{})
+	suite.Run(t, &ExecuteSchemaTestSuite{})
+	suite.Run(t, &ServiceConfigTestSuite{})
+	suite.Run(t, &RequestMetadataTestSuite{})
+	suite.Run(t, &PrepareDedupTestSuite{})
 	suite.Run(t, &TLSTests{Quirks: &FlightSQLQuirks{db: db}})
 }
 
@@ -474,6 +493,86 @@ func (suite *PartitionTests) TestIntrospectPartitions() {
 	suite.Require().Equal(0, len(info.Endpoint[0].Location))
 }
 
+func TestVersionsFromBuildInfo(t *testing.T) {
+	info := &debug.BuildInfo{
+		Deps: []*debug.Module{
+			{Path: "github.com/apache/arrow-adbc/go/adbc", Version: "v0.3.0"},
+			{Path: "github.com/apache/arrow/go/v12", Version: "v12.0.0"},
+			{Path: "google.golang.org/grpc", Version: "v1.53.0"},
+		},
+	}
+
+	driverVersion, arrowVersion := driver.VersionsFromBuildInfo(info, "")
+	require.Equal(t, "v0.3.0", driverVersion)
+	require.Equal(t, "v12.0.0", arrowVersion)
+
+	// a version set with -ldflags is kept
+	driverVersion, arrowVersion = driver.VersionsFromBuildInfo(info, "v1.2.3-custom")
+	require.Equal(t, "v1.2.3-custom", driverVersion)
+	require.Equal(t, "v12.0.0", arrowVersion)
+
+	// only the module itself is recorded in the build info's
+	// dependencies, not the driver's package path
+	driverVersion, _ = driver.VersionsFromBuildInfo(&debug.BuildInfo{
+		Deps: []*debug.Module{
+			{Path: "github.com/apache/arrow-adbc/go/adbc/driver/flightsql", Version: "v0.3.0"},
+		},
+	}, "")
+	require.Equal(t, "", driverVersion)
+}
+
+type InfoTests struct {
+	suite.Suite
+
+	Driver adbc.Driver
+	Quirks validation.DriverQuirks
+
+	DB   adbc.Database
+	Cnxn adbc.Connection
+	ctx  context.Context
+}
+
+func (suite *InfoTests) SetupTest() {
+	suite.Driver = suite.Quirks.SetupDriver(suite.T())
+	var err error
+	suite.DB, err = suite.Driver.NewDatabase(suite.Quirks.DatabaseOptions())
+	suite.Require().NoError(err)
+	suite.ctx = context.Background()
+	suite.Cnxn, err = suite.DB.Open(suite.ctx)
+	suite.Require().NoError(err)
+}
+
+func (suite *InfoTests) TearDownTest() {
+	suite.Require().NoError(suite.Cnxn.Close())
+	suite.Quirks.TearDownDriver(suite.T(), suite.Driver)
+	suite.Cnxn = nil
+	suite.DB = nil
+	suite.Driver = nil
+}
+
+func (suite *InfoTests) TestDriverVersion() {
+	// as if built with -ldflags "-X ...flightsql.infoDriverVersion=v1.2.3"
+	defer driver.SetInfoDriverVersion("v1.2.3")()
+
+	rdr, err := suite.Cnxn.GetInfo(suite.ctx, []adbc.InfoCode{adbc.InfoDriverVersion})
+	suite.Require().NoError(err)
+	defer rdr.Release()
+
+	var versions []string
+	for rdr.Next() {
+		rec := rdr.Record()
+		codes := rec.Column(0).(*array.Uint32)
+		values := rec.Column(1).(*array.DenseUnion)
+		strs := values.Field(0).(*array.String)
+		for i := 0; i < int(rec.NumRows()); i++ {
+			suite.Require().Equal(uint32(adbc.InfoDriverVersion), codes.Value(i))
+			versions = append(versions, strs.Value(int(values.ValueOffset(i))))
+		}
+	}
+	suite.Require().NoError(rdr.Err())
+	suite.Equal([]string{"v1.2.3"}, versions)
+}
+
 type StatementTests struct {
 	suite.Suite
 
@@ -586,6 +685,35 @@ func (suite *HeaderTests) TestDatabaseOptAuthorization() {
 	suite.Contains(suite.Quirks.middle.recordedHeaders.Get("authorization"), "auth-header-token")
 }
 
+func (suite *HeaderTests) TestDefaultUserAgent() {
+	// opening the connection already made a call to the server
+	userAgent := suite.Quirks.middle.recordedHeaders.Get("user-agent")
+	suite.Require().NotEmpty(userAgent)
+	suite.True(strings.HasPrefix(userAgent[0], "ADBC Flight SQL Driver - Go"), userAgent[0])
+}
+
+func (suite *HeaderTests) TestUserAgent() {
+	opts := suite.Quirks.DatabaseOptions()
+	opts[driver.OptionUserAgent] = "my-app/1.0"
+	db, err := suite.Driver.NewDatabase(opts)
+	suite.Require().NoError(err)
+	cnxn, err := db.Open(suite.ctx)
+	suite.Require().NoError(err)
+	defer cnxn.Close()
+
+	found := false
+	for _, userAgent := range suite.Quirks.middle.recordedHeaders.Get("user-agent") {
+		found = found || strings.HasPrefix(userAgent, "my-app/1.0 ")
+	}
+	suite.True(found, suite.Quirks.middle.recordedHeaders.Get("user-agent"))
+
+	for _, val := range []string{"", "caf\u00e9", "line\nbreak"} {
+		opts[driver.OptionUserAgent] = val
+		_, err = suite.Driver.NewDatabase(opts)
+		suite.ErrorContains(err, "Invalid value for database option 'adbc.flight.sql.client.user_agent'")
+	}
+}
+
 func (suite *HeaderTests) TestConnection() {
 	// can't change authorization header on connection, you have to set it
 	// as an option on the database object when creating the connection.
@@ -936,3 +1064,1736 @@ func (suite *TLSTests) TestInvalidOptions() {
 	_, _, err = stmt.ExecuteQuery(suite.ctx)
 	suite.Contains(err.Error(), "Unavailable")
 }
+
+type GetObjectsTestServer struct {
+	flightsql.BaseServer
+}
+
+func flightInfoForCommand(desc *flight.FlightDescriptor, schema *arrow.Schema) *flight.FlightInfo {
+	return &flight.FlightInfo{
+		Endpoint:         []*flight.FlightEndpoint{{Ticket: &flight.Ticket{Ticket: desc.Cmd}}},
+		FlightDescriptor: desc,
+		Schema:           flight.SerializeSchema(schema, memory.DefaultAllocator),
+		TotalRecords:     -1,
+		TotalBytes:       -1,
+	}
+}
+
+func recordChunk(rec arrow.Record) <-chan flight.StreamChunk {
+	ch := make(chan flight.StreamChunk, 1)
+	ch <- flight.StreamChunk{Data: rec}
+	close(ch)
+	return ch
+}
+
+func (srv *GetObjectsTestServer) GetFlightInfoCatalogs(_ context.Context, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	return flightInfoForCommand(desc, schema_ref.Catalogs), nil
+}
+
+func (srv *GetObjectsTestServer) DoGetCatalogs(context.Context) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	catalogs, _, err := array.FromJSON(memory.DefaultAllocator, arrow.BinaryTypes.String, strings.NewReader(`["main"]`))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer catalogs.Release()
+
+	return schema_ref.Catalogs, recordChunk(array.NewRecord(schema_ref.Catalogs, []arrow.Array{catalogs}, 1)), nil
+}
+
+func (srv *GetObjectsTestServer) GetFlightInfoSchemas(_ context.Context, _ flightsql.GetDBSchemas, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	return flightInfoForCommand(desc, schema_ref.DBSchemas), nil
+}
+
+func (srv *GetObjectsTestServer) DoGetDBSchemas(context.Context, flightsql.GetDBSchemas) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, schema_ref.DBSchemas,
+		strings.NewReader(`[{"catalog_name": "main", "db_schema_name": "public"}]`))
+	if err != nil {
+		return nil, nil, err
+	}
+	return schema_ref.DBSchemas, recordChunk(rec), nil
+}
+
+func (srv *GetObjectsTestServer) GetFlightInfoTables(_ context.Context, cmd flightsql.GetTables, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	if cmd.GetIncludeSchema() {
+		return flightInfoForCommand(desc, schema_ref.TablesWithIncludedSchema), nil
+	}
+	return flightInfoForCommand(desc, schema_ref.Tables), nil
+}
+
+func (srv *GetObjectsTestServer) DoGetTables(_ context.Context, cmd flightsql.GetTables) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	tableSchema := arrow.NewSchema([]arrow.Field{
+		{
+			Name: "id", Type: arrow.PrimitiveTypes.Int64, Nullable: false,
+			Metadata: flightsql.NewColumnMetadataBuilder().TypeName("bigint").Precision(19).IsAutoIncrement(true).Metadata(),
+		},
+		{
+			Name: "name", Type: arrow.BinaryTypes.String, Nullable: true,
+			Metadata: flightsql.NewColumnMetadataBuilder().TypeName("varchar").Precision(255).Metadata(),
+		},
+		{Name: "untyped", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+
+	schema := schema_ref.Tables
+	if cmd.GetIncludeSchema() {
+		schema = schema_ref.TablesWithIncludedSchema
+	}
+
+	bldr := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer bldr.Release()
+	bldr.Field(0).(*array.StringBuilder).Append("main")
+	bldr.Field(1).(*array.StringBuilder).Append("public")
+	bldr.Field(2).(*array.StringBuilder).Append("users")
+	bldr.Field(3).(*array.StringBuilder).Append("table")
+	if cmd.GetIncludeSchema() {
+		bldr.Field(4).(*array.BinaryBuilder).Append(flight.SerializeSchema(tableSchema, memory.DefaultAllocator))
+	}
+
+	return schema, recordChunk(bldr.NewRecord()), nil
+}
+
+func (srv *GetObjectsTestServer) GetFlightInfoXdbcTypeInfo(_ context.Context, _ flightsql.GetXdbcTypeInfo, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	return flightInfoForCommand(desc, schema_ref.XdbcTypeInfo), nil
+}
+
+func (srv *GetObjectsTestServer) DoGetXdbcTypeInfo(context.Context, flightsql.GetXdbcTypeInfo) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	return schema_ref.XdbcTypeInfo, recordChunk(example.GetTypeInfoResult(memory.DefaultAllocator)), nil
+}
+
+type GetObjectsTestSuite struct {
+	suite.Suite
+
+	s    flight.Server
+	db   adbc.Database
+	cnxn adbc.Connection
+}
+
+func (suite *GetObjectsTestSuite) SetupSuite() {
+	suite.s = flight.NewServerWithMiddleware(nil)
+	suite.s.RegisterFlightService(flightsql.NewFlightServer(&GetObjectsTestServer{}))
+	suite.Require().NoError(suite.s.Init("localhost:0"))
+	suite.s.SetShutdownOnSignals(os.Interrupt, os.Kill)
+	go func() {
+		_ = suite.s.Serve()
+	}()
+
+	uri := "grpc+tcp://" + suite.s.Addr().String()
+	var err error
+	suite.db, err = (driver.Driver{}).NewDatabase(map[string]string{
+		"uri": uri,
+	})
+	suite.Require().NoError(err)
+}
+
+func (suite *GetObjectsTestSuite) SetupTest() {
+	var err error
+	suite.cnxn, err = suite.db.Open(context.Background())
+	suite.Require().NoError(err)
+}
+
+func (suite *GetObjectsTestSuite) TearDownTest() {
+	suite.Require().NoError(suite.cnxn.Close())
+}
+
+func (suite *GetObjectsTestSuite) TearDownSuite() {
+	suite.db = nil
+	suite.s.Shutdown()
+}
+
+func (suite *GetObjectsTestSuite) TestColumnXdbcMetadata() {
+	rdr, err := suite.cnxn.GetObjects(context.Background(), adbc.ObjectDepthColumns, nil, nil, nil, nil, nil)
+	suite.Require().NoError(err)
+	defer rdr.Release()
+
+	suite.Require().True(rdr.Next())
+	rec := rdr.Record()
+	suite.Require().EqualValues(1, rec.NumRows())
+	suite.Equal("main", rec.Column(0).(*array.String).Value(0))
+
+	dbSchemas := rec.Column(1).(*array.List).ListValues().(*array.Struct)
+	suite.Require().Equal(1, dbSchemas.Len())
+	suite.Equal("public", dbSchemas.Field(0).(*array.String).Value(0))
+
+	tables := dbSchemas.Field(1).(*array.List).ListValues().(*array.Struct)
+	suite.Require().Equal(1, tables.Len())
+	suite.Equal("users", tables.Field(0).(*array.String).Value(0))
+
+	columns := tables.Field(2).(*array.List).ListValues().(*array.Struct)
+	suite.Require().Equal(3, columns.Len())
+
+	columnName := columns.Field(0).(*array.String)
+	xdbcDataType := columns.Field(3).(*array.Int16)
+	xdbcTypeName := columns.Field(4).(*array.String)
+	xdbcColumnSize := columns.Field(5).(*array.Int32)
+	xdbcNullable := columns.Field(8).(*array.Int16)
+	xdbcColumnDef := columns.Field(9).(*array.String)
+	xdbcIsNullable := columns.Field(13).(*array.String)
+	xdbcIsAutoincrement := columns.Field(17).(*array.Boolean)
+
+	suite.Equal("id", columnName.Value(0))
+	suite.Equal("bigint", xdbcTypeName.Value(0))
+	suite.EqualValues(-5, xdbcDataType.Value(0))
+	suite.EqualValues(19, xdbcColumnSize.Value(0))
+	suite.EqualValues(0, xdbcNullable.Value(0))
+	suite.Equal("NO", xdbcIsNullable.Value(0))
+	suite.True(xdbcIsAutoincrement.Value(0))
+	suite.True(xdbcColumnDef.IsNull(0))
+
+	suite.Equal("name", columnName.Value(1))
+	suite.Equal("varchar", xdbcTypeName.Value(1))
+	suite.EqualValues(12, xdbcDataType.Value(1))
+	suite.EqualValues(1, xdbcNullable.Value(1))
+	suite.Equal("YES", xdbcIsNullable.Value(1))
+	suite.True(xdbcIsAutoincrement.IsNull(1))
+
+	// columns without metadata still report nullability
+	suite.Equal("untyped", columnName.Value(2))
+	suite.True(xdbcTypeName.IsNull(2))
+	suite.True(xdbcDataType.IsNull(2))
+	suite.EqualValues(1, xdbcNullable.Value(2))
+	suite.Equal("YES", xdbcIsNullable.Value(2))
+
+	suite.False(rdr.Next())
+	suite.NoError(rdr.Err())
+}
+
+type LocationTestServer struct {
+	flightsql.BaseServer
+
+	locations []string
+}
+
+func (srv *LocationTestServer) GetFlightInfoStatement(_ context.Context, cmd flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	tkt, err := flightsql.CreateStatementQueryTicket([]byte(cmd.GetQuery()))
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := &flight.FlightEndpoint{Ticket: &flight.Ticket{Ticket: tkt}}
+	for _, loc := range srv.locations {
+		endpoint.Location = append(endpoint.Location, &flight.Location{Uri: loc})
+	}
+
+	return &flight.FlightInfo{
+		FlightDescriptor: desc,
+		Endpoint:         []*flight.FlightEndpoint{endpoint},
+		TotalRecords:     -1,
+		TotalBytes:       -1,
+	}, nil
+}
+
+func (srv *LocationTestServer) DoGetStatement(context.Context, flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	schema := arrow.NewSchema([]arrow.Field{{Name: "a", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, schema, strings.NewReader(`[{"a": 42}]`))
+	if err != nil {
+		return nil, nil, err
+	}
+	return schema, recordChunk(rec), nil
+}
+
+type LocationTestSuite struct {
+	suite.Suite
+
+	srv *LocationTestServer
+	s   flight.Server
+	uri string
+}
+
+func (suite *LocationTestSuite) SetupSuite() {
+	suite.srv = &LocationTestServer{}
+	suite.s = flight.NewServerWithMiddleware(nil)
+	suite.s.RegisterFlightService(flightsql.NewFlightServer(suite.srv))
+	suite.Require().NoError(suite.s.Init("localhost:0"))
+	suite.s.SetShutdownOnSignals(os.Interrupt, os.Kill)
+	go func() {
+		_ = suite.s.Serve()
+	}()
+
+	suite.uri = "grpc+tcp://" + suite.s.Addr().String()
+	// the driver should never be able to reach this location by itself
+	suite.srv.locations = []string{"grpc+tcp://internal.invalid:31337"}
+}
+
+func (suite *LocationTestSuite) TearDownSuite() {
+	suite.s.Shutdown()
+}
+
+func (suite *LocationTestSuite) query(opts map[string]string) (int64, error) {
+	opts[adbc.OptionKeyURI] = suite.uri
+	db, err := (driver.Driver{}).NewDatabase(opts)
+	suite.Require().NoError(err)
+
+	cnxn, err := db.Open(context.Background())
+	suite.Require().NoError(err)
+	defer cnxn.Close()
+
+	stmt, err := cnxn.NewStatement()
+	suite.Require().NoError(err)
+	defer stmt.Close()
+
+	suite.Require().NoError(stmt.SetSqlQuery("SELECT 42"))
+	rdr, _, err := stmt.ExecuteQuery(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	defer rdr.Release()
+
+	suite.Require().True(rdr.Next())
+	val := rdr.Record().Column(0).(*array.Int64).Value(0)
+	for rdr.Next() {
+	}
+	return val, rdr.Err()
+}
+
+func (suite *LocationTestSuite) TestUnreachableLocation() {
+	_, err := suite.query(map[string]string{})
+	suite.Error(err)
+}
+
+func (suite *LocationTestSuite) TestRewriteLocation() {
+	val, err := suite.query(map[string]string{
+		driver.OptionLocationRewrite: "other.invalid:1=other.invalid:2, internal.invalid:31337=" + suite.s.Addr().String(),
+	})
+	suite.Require().NoError(err)
+	suite.EqualValues(42, val)
+}
+
+func (suite *LocationTestSuite) TestRewriteHost() {
+	host, port, err := net.SplitHostPort(suite.s.Addr().String())
+	suite.Require().NoError(err)
+
+	// the host alone matches any port; the replacement's port is used
+	val, err := suite.query(map[string]string{
+		driver.OptionLocationRewrite: "internal.invalid=" + suite.s.Addr().String(),
+	})
+	suite.Require().NoError(err)
+	suite.EqualValues(42, val)
+
+	// ...and without one, the location's port is kept
+	locations := suite.srv.locations
+	defer func() { suite.srv.locations = locations }()
+	suite.srv.locations = []string{"grpc+tcp://" + net.JoinHostPort("internal.invalid", port)}
+	val, err = suite.query(map[string]string{
+		driver.OptionLocationRewrite: "internal.invalid=" + host,
+	})
+	suite.Require().NoError(err)
+	suite.EqualValues(42, val)
+
+	// a pair with a port takes precedence
+	val, err = suite.query(map[string]string{
+		driver.OptionLocationRewrite: "internal.invalid=other.invalid, internal.invalid:" + port + "=" + suite.s.Addr().String(),
+	})
+	suite.Require().NoError(err)
+	suite.EqualValues(42, val)
+}
+
+func (suite *LocationTestSuite) TestReuseConnection() {
+	val, err := suite.query(map[string]string{
+		driver.OptionLocationReuseConnection: adbc.OptionValueEnabled,
+	})
+	suite.Require().NoError(err)
+	suite.EqualValues(42, val)
+}
+
+func (suite *LocationTestSuite) TestSchemePreference() {
+	locations := suite.srv.locations
+	defer func() { suite.srv.locations = locations }()
+	// the plaintext location is reachable, the TLS one is not
+	suite.srv.locations = []string{suite.uri, "grpc+tls://internal.invalid:31337"}
+
+	val, err := suite.query(map[string]string{
+		driver.OptionLocationSchemePreference: "grpc+tls, grpc+tcp",
+	})
+	suite.Require().NoError(err)
+	suite.EqualValues(42, val)
+
+	// plaintext is refused even though it is reachable
+	_, err = suite.query(map[string]string{
+		driver.OptionLocationSchemePreference: "grpc+tls",
+	})
+	suite.Error(err)
+
+	suite.srv.locations = []string{suite.uri}
+	_, err = suite.query(map[string]string{
+		driver.OptionLocationSchemePreference: "grpc+tls",
+	})
+	var adbcErr adbc.Error
+	suite.Require().ErrorAs(err, &adbcErr)
+	suite.Equal(adbc.StatusIO, adbcErr.Code)
+	suite.Contains(adbcErr.Msg, driver.OptionLocationSchemePreference)
+}
+
+func (suite *LocationTestSuite) TestSchemePreferenceReuseConnection() {
+	locations := suite.srv.locations
+	defer func() { suite.srv.locations = locations }()
+	suite.srv.locations = []string{"grpc+tls://internal.invalid:31337"}
+
+	// the location is fetched from the database's plaintext URI, so it
+	// is refused even though the server returned a TLS location
+	_, err := suite.query(map[string]string{
+		driver.OptionLocationReuseConnection:  adbc.OptionValueEnabled,
+		driver.OptionLocationSchemePreference: "grpc+tls",
+	})
+	var adbcErr adbc.Error
+	suite.Require().ErrorAs(err, &adbcErr)
+	suite.Equal(adbc.StatusIO, adbcErr.Code)
+	suite.Contains(adbcErr.Msg, driver.OptionLocationSchemePreference)
+
+	val, err := suite.query(map[string]string{
+		driver.OptionLocationReuseConnection:  adbc.OptionValueEnabled,
+		driver.OptionLocationSchemePreference: "grpc+tcp",
+	})
+	suite.Require().NoError(err)
+	suite.EqualValues(42, val)
+}
+
+func (suite *LocationTestSuite) TestInvalidOptions() {
+	_, err := (driver.Driver{}).NewDatabase(map[string]string{
+		adbc.OptionKeyURI:            suite.uri,
+		driver.OptionLocationRewrite: "internal.invalid:31337",
+	})
+	suite.ErrorContains(err, "Invalid value for database option 'adbc.flight.sql.location.rewrite'")
+
+	_, err = (driver.Driver{}).NewDatabase(map[string]string{
+		adbc.OptionKeyURI:                    suite.uri,
+		driver.OptionLocationReuseConnection: "invalid",
+	})
+	suite.ErrorContains(err, "Invalid value for database option 'adbc.flight.sql.location.reuse_connection': 'invalid'")
+
+	_, err = (driver.Driver{}).NewDatabase(map[string]string{
+		adbc.OptionKeyURI:                     suite.uri,
+		driver.OptionLocationSchemePreference: "grpc+tls,,grpc",
+	})
+	suite.ErrorContains(err, "Invalid value for database option 'adbc.flight.sql.location.scheme_preference'")
+}
+
+type CrossReferenceTestServer struct {
+	flightsql.BaseServer
+}
+
+func (srv *CrossReferenceTestServer) GetFlightInfoCrossReference(_ context.Context, _ flightsql.CrossTableRef, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	return flightInfoForCommand(desc, schema_ref.CrossReference), nil
+}
+
+func (srv *CrossReferenceTestServer) DoGetCrossReference(_ context.Context, cmd flightsql.CrossTableRef) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	bldr := array.NewRecordBuilder(memory.DefaultAllocator, schema_ref.CrossReference)
+	defer bldr.Release()
+
+	appendRef := func(offset int, ref flightsql.TableRef, column string) {
+		if ref.Catalog != nil {
+			bldr.Field(offset).(*array.StringBuilder).Append(*ref.Catalog)
+		} else {
+			bldr.Field(offset).AppendNull()
+		}
+		if ref.DBSchema != nil {
+			bldr.Field(offset + 1).(*array.StringBuilder).Append(*ref.DBSchema)
+		} else {
+			bldr.Field(offset + 1).AppendNull()
+		}
+		bldr.Field(offset + 2).(*array.StringBuilder).Append(ref.Table)
+		bldr.Field(offset + 3).(*array.StringBuilder).Append(column)
+	}
+	// echo the requested tables back so the test can check the arguments
+	appendRef(0, cmd.PKRef, "id")
+	appendRef(4, cmd.FKRef, "user_id")
+	bldr.Field(8).(*array.Int32Builder).Append(1)
+	bldr.Field(9).(*array.StringBuilder).Append("orders_user_id_fkey")
+	bldr.Field(10).(*array.StringBuilder).Append("users_pkey")
+	bldr.Field(11).(*array.Uint8Builder).Append(0)
+	bldr.Field(12).(*array.Uint8Builder).Append(1)
+
+	return schema_ref.CrossReference, recordChunk(bldr.NewRecord()), nil
+}
+
+type CrossReferenceTestSuite struct {
+	suite.Suite
+
+	s    flight.Server
+	db   adbc.Database
+	cnxn adbc.Connection
+}
+
+func (suite *CrossReferenceTestSuite) SetupSuite() {
+	suite.s = flight.NewServerWithMiddleware(nil)
+	suite.s.RegisterFlightService(flightsql.NewFlightServer(&CrossReferenceTestServer{}))
+	suite.Require().NoError(suite.s.Init("localhost:0"))
+	suite.s.SetShutdownOnSignals(os.Interrupt, os.Kill)
+	go func() {
+		_ = suite.s.Serve()
+	}()
+
+	uri := "grpc+tcp://" + suite.s.Addr().String()
+	var err error
+	suite.db, err = (driver.Driver{}).NewDatabase(map[string]string{
+		"uri": uri,
+	})
+	suite.Require().NoError(err)
+}
+
+func (suite *CrossReferenceTestSuite) SetupTest() {
+	var err error
+	suite.cnxn, err = suite.db.Open(context.Background())
+	suite.Require().NoError(err)
+}
+
+func (suite *CrossReferenceTestSuite) TearDownTest() {
+	suite.Require().NoError(suite.cnxn.Close())
+}
+
+func (suite *CrossReferenceTestSuite) TearDownSuite() {
+	suite.db = nil
+	suite.s.Shutdown()
+}
+
+func (suite *CrossReferenceTestSuite) TestCrossReference() {
+	cnxn, ok := suite.cnxn.(driver.Connection)
+	suite.Require().True(ok)
+
+	catalog, dbSchema := "main", "public"
+	rdr, err := cnxn.GetCrossReference(context.Background(), &catalog, &dbSchema, "users", nil, nil, "orders")
+	suite.Require().NoError(err)
+	defer rdr.Release()
+
+	suite.True(schema_ref.CrossReference.Equal(rdr.Schema()))
+	suite.Require().True(rdr.Next())
+	rec := rdr.Record()
+	suite.Require().EqualValues(1, rec.NumRows())
+
+	suite.Equal("main", rec.Column(0).(*array.String).Value(0))
+	suite.Equal("public", rec.Column(1).(*array.String).Value(0))
+	suite.Equal("users", rec.Column(2).(*array.String).Value(0))
+	suite.Equal("id", rec.Column(3).(*array.String).Value(0))
+	suite.True(rec.Column(4).IsNull(0))
+	suite.True(rec.Column(5).IsNull(0))
+	suite.Equal("orders", rec.Column(6).(*array.String).Value(0))
+	suite.Equal("user_id", rec.Column(7).(*array.String).Value(0))
+	suite.EqualValues(1, rec.Column(8).(*array.Int32).Value(0))
+	suite.Equal("orders_user_id_fkey", rec.Column(9).(*array.String).Value(0))
+
+	suite.False(rdr.Next())
+	suite.NoError(rdr.Err())
+}
+
+func (suite *CrossReferenceTestSuite) TestNotImplemented() {
+	// a server that does not implement the command
+	s := flight.NewServerWithMiddleware(nil)
+	s.RegisterFlightService(flightsql.NewFlightServer(&flightsql.BaseServer{}))
+	suite.Require().NoError(s.Init("localhost:0"))
+	go func() {
+		_ = s.Serve()
+	}()
+	defer s.Shutdown()
+
+	db, err := (driver.Driver{}).NewDatabase(map[string]string{
+		"uri": "grpc+tcp://" + s.Addr().String(),
+	})
+	suite.Require().NoError(err)
+	cnxn, err := db.Open(context.Background())
+	suite.Require().NoError(err)
+	defer cnxn.Close()
+
+	_, err = cnxn.(driver.Connection).GetCrossReference(context.Background(), nil, nil, "users", nil, nil, "orders")
+	var adbcErr adbc.Error
+	suite.Require().ErrorAs(err, &adbcErr)
+	suite.Equal(adbc.StatusNotImplemented, adbcErr.Code)
+}
+
+// ExchangeTestServer echoes the records sent to it through DoExchange
+// if the descriptor command is "echo".
+type ExchangeTestServer struct {
+	flight.FlightServer
+}
+
+func (srv *ExchangeTestServer) DoExchange(stream flight.FlightService_DoExchangeServer) error {
+	rdr, err := flight.NewRecordReader(stream)
+	if err != nil {
+		return err
+	}
+	defer rdr.Release()
+
+	if desc := rdr.LatestFlightDescriptor(); desc == nil || string(desc.Cmd) != "echo" {
+		return status.Error(codes.InvalidArgument, "unknown exchange command")
+	}
+
+	wr := flight.NewRecordWriter(stream, ipc.WithSchema(rdr.Schema()))
+	defer wr.Close()
+	for rdr.Next() {
+		if err := wr.Write(rdr.Record()); err != nil {
+			return err
+		}
+	}
+	return rdr.Err()
+}
+
+type ExchangeTestSuite struct {
+	suite.Suite
+
+	s    flight.Server
+	db   adbc.Database
+	cnxn adbc.Connection
+}
+
+func (suite *ExchangeTestSuite) SetupSuite() {
+	suite.s = flight.NewServerWithMiddleware(nil)
+	suite.s.RegisterFlightService(&ExchangeTestServer{flightsql.NewFlightServer(&flightsql.BaseServer{})})
+	suite.Require().NoError(suite.s.Init("localhost:0"))
+	suite.s.SetShutdownOnSignals(os.Interrupt, os.Kill)
+	go func() {
+		_ = suite.s.Serve()
+	}()
+
+	uri := "grpc+tcp://" + suite.s.Addr().String()
+	var err error
+	suite.db, err = (driver.Driver{}).NewDatabase(map[string]string{
+		"uri": uri,
+	})
+	suite.Require().NoError(err)
+}
+
+func (suite *ExchangeTestSuite) SetupTest() {
+	var err error
+	suite.cnxn, err = suite.db.Open(context.Background())
+	suite.Require().NoError(err)
+}
+
+func (suite *ExchangeTestSuite) TearDownTest() {
+	suite.Require().NoError(suite.cnxn.Close())
+}
+
+func (suite *ExchangeTestSuite) TearDownSuite() {
+	suite.db = nil
+	suite.s.Shutdown()
+}
+
+func (suite *ExchangeTestSuite) input() array.RecordReader {
+	schema := arrow.NewSchema([]arrow.Field{{Name: "a", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	var recs []arrow.Record
+	for _, data := range []string{`[{"a": 1}, {"a": 2}]`, `[{"a": 3}]`} {
+		rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, schema, strings.NewReader(data))
+		suite.Require().NoError(err)
+		defer rec.Release()
+		recs = append(recs, rec)
+	}
+	rdr, err := array.NewRecordReader(schema, recs)
+	suite.Require().NoError(err)
+	return rdr
+}
+
+func (suite *ExchangeTestSuite) TestEcho() {
+	cnxn, ok := suite.cnxn.(driver.Connection)
+	suite.Require().True(ok)
+
+	desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("echo")}
+	rdr, err := cnxn.DoExchange(context.Background(), desc, suite.input())
+	suite.Require().NoError(err)
+	defer rdr.Release()
+
+	var values []int64
+	for rdr.Next() {
+		values = append(values, rdr.Record().Column(0).(*array.Int64).Int64Values()...)
+	}
+	suite.Require().NoError(rdr.Err())
+	suite.Equal([]int64{1, 2, 3}, values)
+}
+
+func (suite *ExchangeTestSuite) TestServerError() {
+	cnxn := suite.cnxn.(driver.Connection)
+
+	desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("unknown")}
+	rdr, err := cnxn.DoExchange(context.Background(), desc, suite.input())
+	if err == nil {
+		for rdr.Next() {
+		}
+		err = rdr.Err()
+		rdr.Release()
+	}
+	var adbcErr adbc.Error
+	suite.Require().ErrorAs(err, &adbcErr)
+	suite.Equal(adbc.StatusInvalidArgument, adbcErr.Code)
+	suite.Contains(adbcErr.Msg, "unknown exchange command")
+}
+
+// ResultSizeTestServer reports the size of every result in its
+// FlightInfo.
+type ResultSizeTestServer struct {
+	LocationTestServer
+}
+
+func (srv *ResultSizeTestServer) GetFlightInfoStatement(ctx context.Context, cmd flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	info, err := srv.LocationTestServer.GetFlightInfoStatement(ctx, cmd, desc)
+	if err != nil {
+		return nil, err
+	}
+	info.TotalRecords = 1
+	info.TotalBytes = 8
+	return info, nil
+}
+
+type ResultSizeTestSuite struct {
+	suite.Suite
+
+	s    flight.Server
+	db   adbc.Database
+	cnxn adbc.Connection
+	stmt adbc.Statement
+}
+
+func (suite *ResultSizeTestSuite) SetupSuite() {
+	suite.s = flight.NewServerWithMiddleware(nil)
+	suite.s.RegisterFlightService(flightsql.NewFlightServer(&ResultSizeTestServer{}))
+	suite.Require().NoError(suite.s.Init("localhost:0"))
+	suite.s.SetShutdownOnSignals(os.Interrupt, os.Kill)
+	go func() {
+		_ = suite.s.Serve()
+	}()
+
+	uri := "grpc+tcp://" + suite.s.Addr().String()
+	var err error
+	suite.db, err = (driver.Driver{}).NewDatabase(map[string]string{
+		"uri": uri,
+	})
+	suite.Require().NoError(err)
+}
+
+func (suite *ResultSizeTestSuite) SetupTest() {
+	var err error
+	suite.cnxn, err = suite.db.Open(context.Background())
+	suite.Require().NoError(err)
+	suite.stmt, err = suite.cnxn.NewStatement()
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.stmt.SetSqlQuery("SELECT 42"))
+}
+
+func (suite *ResultSizeTestSuite) TearDownTest() {
+	suite.Require().NoError(suite.stmt.Close())
+	suite.Require().NoError(suite.cnxn.Close())
+}
+
+func (suite *ResultSizeTestSuite) TearDownSuite() {
+	suite.db = nil
+	suite.s.Shutdown()
+}
+
+func (suite *ResultSizeTestSuite) TestUnknownBeforeExecute() {
+	totalRecords, totalBytes := suite.stmt.(driver.Statement).ResultSize()
+	suite.EqualValues(-1, totalRecords)
+	suite.EqualValues(-1, totalBytes)
+}
+
+func (suite *ResultSizeTestSuite) TestExecuteQuery() {
+	rdr, nrec, err := suite.stmt.ExecuteQuery(context.Background())
+	suite.Require().NoError(err)
+	defer rdr.Release()
+
+	totalRecords, totalBytes := suite.stmt.(driver.Statement).ResultSize()
+	suite.EqualValues(1, totalRecords)
+	suite.EqualValues(8, totalBytes)
+	suite.Equal(nrec, totalRecords)
+}
+
+func (suite *ResultSizeTestSuite) TestExecutePartitions() {
+	_, _, _, err := suite.stmt.ExecutePartitions(context.Background())
+	suite.Require().NoError(err)
+
+	totalRecords, totalBytes := suite.stmt.(driver.Statement).ResultSize()
+	suite.EqualValues(1, totalRecords)
+	suite.EqualValues(8, totalBytes)
+}
+
+// StreamLimitTestServer keeps the result stream of the query "block"
+// open until release is closed. Other queries return immediately.
+type StreamLimitTestServer struct {
+	GetObjectsTestServer
+
+	release chan struct{}
+}
+
+func (srv *StreamLimitTestServer) GetFlightInfoStatement(_ context.Context, cmd flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	tkt, err := flightsql.CreateStatementQueryTicket([]byte(cmd.GetQuery()))
+	if err != nil {
+		return nil, err
+	}
+
+	schema := arrow.NewSchema([]arrow.Field{{Name: "a", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	info := &flight.FlightInfo{
+		FlightDescriptor: desc,
+		Endpoint:         []*flight.FlightEndpoint{{Ticket: &flight.Ticket{Ticket: tkt}}},
+		Schema:           flight.SerializeSchema(schema, memory.DefaultAllocator),
+		TotalRecords:     -1,
+		TotalBytes:       -1,
+	}
+	if cmd.GetQuery() == "partitions" {
+		// more endpoints than the limit, each with more batches than
+		// the statement's queue size
+		info.Endpoint = nil
+		for i := 0; i < streamLimitPartitions; i++ {
+			info.Endpoint = append(info.Endpoint, &flight.FlightEndpoint{Ticket: &flight.Ticket{Ticket: tkt}})
+		}
+	}
+	return info, nil
+}
+
+const (
+	streamLimitPartitions = 8
+	streamLimitBatches    = 20
+)
+
+func (srv *StreamLimitTestServer) DoGetStatement(ctx context.Context, tkt flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	schema := arrow.NewSchema([]arrow.Field{{Name: "a", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, schema, strings.NewReader(`[{"a": 42}]`))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch string(tkt.GetStatementHandle()) {
+	case "block":
+	case "partitions":
+		ch := make(chan flight.StreamChunk)
+		go func() {
+			defer close(ch)
+			defer rec.Release()
+			for i := 0; i < streamLimitBatches; i++ {
+				rec.Retain()
+				select {
+				case ch <- flight.StreamChunk{Data: rec}:
+				case <-ctx.Done():
+					rec.Release()
+					return
+				}
+			}
+		}()
+		return schema, ch, nil
+	default:
+		return schema, recordChunk(rec), nil
+	}
+
+	ch := make(chan flight.StreamChunk, 1)
+	ch <- flight.StreamChunk{Data: rec}
+	go func() {
+		defer close(ch)
+		select {
+		case <-srv.release:
+		case <-ctx.Done():
+		}
+	}()
+	return schema, ch, nil
+}
+
+type StreamLimitTestSuite struct {
+	suite.Suite
+
+	srv  *StreamLimitTestServer
+	s    flight.Server
+	db   adbc.Database
+	cnxn adbc.Connection
+}
+
+func (suite *StreamLimitTestSuite) SetupTest() {
+	srv := &StreamLimitTestServer{release: make(chan struct{})}
+	s := flight.NewServerWithMiddleware(nil)
+	s.RegisterFlightService(flightsql.NewFlightServer(srv))
+	suite.Require().NoError(s.Init("localhost:0"))
+	go func() {
+		_ = s.Serve()
+	}()
+	suite.srv, suite.s = srv, s
+
+	var err error
+	suite.db, err = (driver.Driver{}).NewDatabase(map[string]string{
+		adbc.OptionKeyURI:                 "grpc+tcp://" + s.Addr().String(),
+		driver.OptionMaxConcurrentStreams: "1",
+	})
+	suite.Require().NoError(err)
+	suite.cnxn, err = suite.db.Open(context.Background())
+	suite.Require().NoError(err)
+}
+
+func (suite *StreamLimitTestSuite) TearDownTest() {
+	suite.Require().NoError(suite.cnxn.Close())
+	suite.s.Shutdown()
+}
+
+func (suite *StreamLimitTestSuite) execute(ctx context.Context, query string) array.RecordReader {
+	stmt, err := suite.cnxn.NewStatement()
+	suite.Require().NoError(err)
+	defer stmt.Close()
+
+	suite.Require().NoError(stmt.SetSqlQuery(query))
+	rdr, _, err := stmt.ExecuteQuery(ctx)
+	suite.Require().NoError(err)
+	return rdr
+}
+
+func (suite *StreamLimitTestSuite) TestQueuedUntilStreamCompletes() {
+	blocking := suite.execute(context.Background(), "block")
+	defer blocking.Release()
+	suite.Require().True(blocking.Next())
+
+	queued := suite.execute(context.Background(), "fast")
+	defer queued.Release()
+	done := make(chan bool)
+	go func() {
+		done <- queued.Next()
+	}()
+
+	select {
+	case <-done:
+		suite.FailNow("second stream was opened while the first was still open")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	close(suite.srv.release)
+	suite.False(blocking.Next())
+	suite.NoError(blocking.Err())
+
+	select {
+	case ok := <-done:
+		suite.True(ok)
+		suite.EqualValues(42, queued.Record().Column(0).(*array.Int64).Value(0))
+	case <-time.After(5 * time.Second):
+		suite.FailNow("second stream was not opened after the first completed")
+	}
+}
+
+func (suite *StreamLimitTestSuite) TestQueuedCancellation() {
+	blocking := suite.execute(context.Background(), "block")
+	defer blocking.Release()
+	suite.Require().True(blocking.Next())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	queued := suite.execute(ctx, "fast")
+	defer queued.Release()
+
+	suite.False(queued.Next())
+	suite.Equal(codes.DeadlineExceeded, status.Code(queued.Err()))
+
+	close(suite.srv.release)
+}
+
+func (suite *StreamLimitTestSuite) TestMorePartitionsThanStreams() {
+	rdr := suite.execute(context.Background(), "partitions")
+	defer rdr.Release()
+
+	done := make(chan int)
+	go func() {
+		rows := 0
+		for rdr.Next() {
+			rows += int(rdr.Record().NumRows())
+		}
+		done <- rows
+	}()
+
+	select {
+	case rows := <-done:
+		suite.NoError(rdr.Err())
+		suite.Equal(streamLimitPartitions*streamLimitBatches, rows)
+	case <-time.After(10 * time.Second):
+		suite.FailNow("reading partitions deadlocked on the stream limit")
+	}
+}
+
+func (suite *StreamLimitTestSuite) TestAbandonedStreams() {
+	// streams that are not read to the end must not hold a slot past
+	// their reader's Release; otherwise these calls wait until the
+	// deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// GetTableSchema only reads the first batch
+	for i := 0; i < 3; i++ {
+		schema, err := suite.cnxn.GetTableSchema(ctx, nil, nil, "users")
+		suite.Require().NoError(err)
+		suite.Equal(3, len(schema.Fields()))
+	}
+
+	stmt, err := suite.cnxn.NewStatement()
+	suite.Require().NoError(err)
+	defer stmt.Close()
+	suite.Require().NoError(stmt.SetSqlQuery("fast"))
+	_, partitions, _, err := stmt.ExecutePartitions(ctx)
+	suite.Require().NoError(err)
+	suite.Require().Len(partitions.PartitionIDs, 1)
+
+	// a partition reader released without being read
+	for i := 0; i < 3; i++ {
+		rdr, err := suite.cnxn.ReadPartition(ctx, partitions.PartitionIDs[0])
+		suite.Require().NoError(err)
+		rdr.Release()
+	}
+
+	rdr, err := suite.cnxn.ReadPartition(ctx, partitions.PartitionIDs[0])
+	suite.Require().NoError(err)
+	defer rdr.Release()
+	suite.Require().True(rdr.Next())
+	suite.EqualValues(42, rdr.Record().Column(0).(*array.Int64).Value(0))
+}
+
+func (suite *StreamLimitTestSuite) TestInvalidOption() {
+	for _, val := range []string{"0", "-1", "many"} {
+		_, err := (driver.Driver{}).NewDatabase(map[string]string{
+			adbc.OptionKeyURI:                 "grpc+tcp://" + suite.s.Addr().String(),
+			driver.OptionMaxConcurrentStreams: val,
+		})
+		var adbcErr adbc.Error
+		suite.Require().ErrorAs(err, &adbcErr)
+		suite.Equal(adbc.StatusInvalidArgument, adbcErr.Code)
+	}
+}
+
+// ExecuteSchemaTestServer counts DoGet calls. The FlightInfo for the
+// query "no schema" does not include a schema.
+type ExecuteSchemaTestServer struct {
+	flightsql.BaseServer
+
+	doGets int32
+}
+
+func (srv *ExecuteSchemaTestServer) GetFlightInfoStatement(_ context.Context, cmd flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	tkt, err := flightsql.CreateStatementQueryTicket([]byte(cmd.GetQuery()))
+	if err != nil {
+		return nil, err
+	}
+
+	info := &flight.FlightInfo{
+		FlightDescriptor: desc,
+		Endpoint:         []*flight.FlightEndpoint{{Ticket: &flight.Ticket{Ticket: tkt}}},
+		TotalRecords:     -1,
+		TotalBytes:       -1,
+	}
+	if cmd.GetQuery() != "no schema" {
+		schema := arrow.NewSchema([]arrow.Field{{Name: "a", Type: arrow.PrimitiveTypes.Int64, Nullable: true}}, nil)
+		info.Schema = flight.SerializeSchema(schema, memory.DefaultAllocator)
+	}
+	return info, nil
+}
+
+func (srv *ExecuteSchemaTestServer) DoGetStatement(context.Context, flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	atomic.AddInt32(&srv.doGets, 1)
+	return nil, nil, status.Error(codes.Internal, "ExecuteSchema must not fetch data")
+}
+
+type ExecuteSchemaTestSuite struct {
+	suite.Suite
+
+	srv  *ExecuteSchemaTestServer
+	s    flight.Server
+	db   adbc.Database
+	cnxn adbc.Connection
+}
+
+func (suite *ExecuteSchemaTestSuite) SetupSuite() {
+	suite.srv = &ExecuteSchemaTestServer{}
+	suite.s = flight.NewServerWithMiddleware(nil)
+	suite.s.RegisterFlightService(flightsql.NewFlightServer(suite.srv))
+	suite.Require().NoError(suite.s.Init("localhost:0"))
+	suite.s.SetShutdownOnSignals(os.Interrupt, os.Kill)
+	go func() {
+		_ = suite.s.Serve()
+	}()
+
+	uri := "grpc+tcp://" + suite.s.Addr().String()
+	var err error
+	suite.db, err = (driver.Driver{}).NewDatabase(map[string]string{
+		"uri": uri,
+	})
+	suite.Require().NoError(err)
+}
+
+func (suite *ExecuteSchemaTestSuite) SetupTest() {
+	var err error
+	suite.cnxn, err = suite.db.Open(context.Background())
+	suite.Require().NoError(err)
+}
+
+func (suite *ExecuteSchemaTestSuite) TearDownTest() {
+	suite.Require().NoError(suite.cnxn.Close())
+}
+
+func (suite *ExecuteSchemaTestSuite) TearDownSuite() {
+	suite.db = nil
+	suite.s.Shutdown()
+}
+
+func (suite *ExecuteSchemaTestSuite) executeSchema(query string) (*arrow.Schema, error) {
+	stmt, err := suite.cnxn.NewStatement()
+	suite.Require().NoError(err)
+	defer stmt.Close()
+
+	suite.Require().NoError(stmt.SetSqlQuery(query))
+	return stmt.(driver.Statement).ExecuteSchema(context.Background())
+}
+
+func (suite *ExecuteSchemaTestSuite) TestNoDoGet() {
+	schema, err := suite.executeSchema("SELECT a FROM t")
+	suite.Require().NoError(err)
+
+	expected := arrow.NewSchema([]arrow.Field{{Name: "a", Type: arrow.PrimitiveTypes.Int64, Nullable: true}}, nil)
+	suite.Truef(expected.Equal(schema), "expected %s, got %s", expected, schema)
+	suite.EqualValues(0, atomic.LoadInt32(&suite.srv.doGets))
+}
+
+func (suite *ExecuteSchemaTestSuite) TestNoSchema() {
+	_, err := suite.executeSchema("no schema")
+	var adbcErr adbc.Error
+	suite.Require().ErrorAs(err, &adbcErr)
+	suite.Equal(adbc.StatusInternal, adbcErr.Code)
+	suite.EqualValues(0, atomic.LoadInt32(&suite.srv.doGets))
+}
+
+// RetryTestServer fails GetFlightInfo for queries with Unavailable
+// until it has been called failures times.
+type RetryTestServer struct {
+	LocationTestServer
+
+	failures int32
+	attempts int32
+}
+
+func (srv *RetryTestServer) GetFlightInfoStatement(ctx context.Context, cmd flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	if atomic.AddInt32(&srv.attempts, 1) <= atomic.LoadInt32(&srv.failures) {
+		return nil, status.Error(codes.Unavailable, "try again")
+	}
+	return srv.LocationTestServer.GetFlightInfoStatement(ctx, cmd, desc)
+}
+
+type ServiceConfigTestSuite struct {
+	suite.Suite
+
+	srv *RetryTestServer
+	s   flight.Server
+	uri string
+}
+
+func (suite *ServiceConfigTestSuite) SetupSuite() {
+	suite.srv = &RetryTestServer{}
+	suite.s = flight.NewServerWithMiddleware(nil)
+	suite.s.RegisterFlightService(flightsql.NewFlightServer(suite.srv))
+	suite.Require().NoError(suite.s.Init("localhost:0"))
+	suite.s.SetShutdownOnSignals(os.Interrupt, os.Kill)
+	go func() {
+		_ = suite.s.Serve()
+	}()
+
+	suite.uri = "grpc+tcp://" + suite.s.Addr().String()
+}
+
+func (suite *ServiceConfigTestSuite) SetupTest() {
+	atomic.StoreInt32(&suite.srv.failures, 2)
+	atomic.StoreInt32(&suite.srv.attempts, 0)
+}
+
+func (suite *ServiceConfigTestSuite) TearDownSuite() {
+	suite.s.Shutdown()
+}
+
+func (suite *ServiceConfigTestSuite) query(opts map[string]string) error {
+	opts[adbc.OptionKeyURI] = suite.uri
+	db, err := (driver.Driver{}).NewDatabase(opts)
+	suite.Require().NoError(err)
+
+	cnxn, err := db.Open(context.Background())
+	suite.Require().NoError(err)
+	defer cnxn.Close()
+
+	stmt, err := cnxn.NewStatement()
+	suite.Require().NoError(err)
+	defer stmt.Close()
+
+	suite.Require().NoError(stmt.SetSqlQuery("SELECT 42"))
+	rdr, _, err := stmt.ExecuteQuery(context.Background())
+	if err != nil {
+		return err
+	}
+	rdr.Release()
+	return nil
+}
+
+func (suite *ServiceConfigTestSuite) TestNoRetryByDefault() {
+	var adbcErr adbc.Error
+	suite.Require().ErrorAs(suite.query(map[string]string{}), &adbcErr)
+	suite.Equal(adbc.StatusIO, adbcErr.Code)
+	suite.EqualValues(1, atomic.LoadInt32(&suite.srv.attempts))
+}
+
+func (suite *ServiceConfigTestSuite) TestRetryPolicy() {
+	suite.Require().NoError(suite.query(map[string]string{
+		driver.OptionServiceConfig: `{
+			"methodConfig": [{
+				"name": [{"service": "arrow.flight.protocol.FlightService", "method": "GetFlightInfo"}],
+				"retryPolicy": {
+					"maxAttempts": 3,
+					"initialBackoff": "0.01s",
+					"maxBackoff": "0.1s",
+					"backoffMultiplier": 2,
+					"retryableStatusCodes": ["UNAVAILABLE"]
+				}
+			}]
+		}`,
+	}))
+	suite.EqualValues(3, atomic.LoadInt32(&suite.srv.attempts))
+}
+
+func (suite *ServiceConfigTestSuite) TestInvalidServiceConfig() {
+	_, err := (driver.Driver{}).NewDatabase(map[string]string{
+		adbc.OptionKeyURI:          suite.uri,
+		driver.OptionServiceConfig: `{"methodConfig": [`,
+	})
+	suite.ErrorContains(err, "Invalid value for database option 'adbc.flight.sql.rpc.service_config'")
+}
+
+// nonceRecorder records the x-nonce header of every call it sees.
+type nonceRecorder struct {
+	mu     sync.Mutex
+	nonces []string
+}
+
+func (n *nonceRecorder) StartCall(ctx context.Context) context.Context {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		n.mu.Lock()
+		n.nonces = append(n.nonces, md.Get("x-nonce")...)
+		n.mu.Unlock()
+	}
+	return ctx
+}
+
+func (n *nonceRecorder) CallCompleted(context.Context, error) {}
+
+func (n *nonceRecorder) Nonces() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]string(nil), n.nonces...)
+}
+
+type RequestMetadataTestSuite struct {
+	suite.Suite
+
+	recorder *nonceRecorder
+	s        flight.Server
+	uri      string
+}
+
+func (suite *RequestMetadataTestSuite) SetupTest() {
+	suite.recorder = &nonceRecorder{}
+	s := flight.NewServerWithMiddleware([]flight.ServerMiddleware{flight.CreateServerMiddleware(suite.recorder)})
+	s.RegisterFlightService(flightsql.NewFlightServer(&LocationTestServer{}))
+	suite.Require().NoError(s.Init("localhost:0"))
+	go func() {
+		_ = s.Serve()
+	}()
+	suite.s = s
+	suite.uri = "grpc+tcp://" + s.Addr().String()
+}
+
+func (suite *RequestMetadataTestSuite) TearDownTest() {
+	suite.s.Shutdown()
+}
+
+func (suite *RequestMetadataTestSuite) query(drv driver.Driver) error {
+	db, err := drv.NewDatabase(map[string]string{adbc.OptionKeyURI: suite.uri})
+	suite.Require().NoError(err)
+
+	cnxn, err := db.Open(context.Background())
+	if err != nil {
+		return err
+	}
+	defer cnxn.Close()
+
+	stmt, err := cnxn.NewStatement()
+	suite.Require().NoError(err)
+	defer stmt.Close()
+
+	suite.Require().NoError(stmt.SetSqlQuery("SELECT 42"))
+	rdr, _, err := stmt.ExecuteQuery(context.Background())
+	if err != nil {
+		return err
+	}
+	defer rdr.Release()
+
+	for rdr.Next() {
+	}
+	return rdr.Err()
+}
+
+func (suite *RequestMetadataTestSuite) TestNonce() {
+	var (
+		mu      sync.Mutex
+		methods []string
+		count   int
+	)
+	drv := driver.Driver{
+		RequestMetadata: func(_ context.Context, method string) (metadata.MD, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			count++
+			methods = append(methods, method)
+			return metadata.Pairs("x-nonce", fmt.Sprintf("nonce-%d", count)), nil
+		},
+	}
+	suite.Require().NoError(suite.query(drv))
+
+	mu.Lock()
+	defer mu.Unlock()
+	suite.Contains(methods, "/arrow.flight.protocol.FlightService/GetFlightInfo")
+	suite.Contains(methods, "/arrow.flight.protocol.FlightService/DoGet")
+
+	// every call carried a fresh nonce
+	nonces := suite.recorder.Nonces()
+	suite.Len(nonces, count)
+	seen := make(map[string]bool)
+	for _, nonce := range nonces {
+		suite.False(seen[nonce], "nonce %s was reused", nonce)
+		seen[nonce] = true
+	}
+}
+
+func (suite *RequestMetadataTestSuite) TestCallbackError() {
+	drv := driver.Driver{
+		RequestMetadata: func(_ context.Context, method string) (metadata.MD, error) {
+			if strings.HasSuffix(method, "/GetFlightInfo") {
+				return nil, adbc.Error{Msg: "cannot sign request", Code: adbc.StatusUnauthenticated}
+			}
+			return metadata.Pairs("x-nonce", "static"), nil
+		},
+	}
+	err := suite.query(drv)
+	var adbcErr adbc.Error
+	suite.Require().ErrorAs(err, &adbcErr)
+	suite.Equal(adbc.StatusUnauthenticated, adbcErr.Code)
+	suite.Equal("cannot sign request", adbcErr.Msg)
+
+	// the query itself never reached the server
+	for _, nonce := range suite.recorder.Nonces() {
+		suite.Equal("static", nonce)
+	}
+}
+
+// PrepareDedupTestServer counts prepared statements created and closed.
+// Creating a statement is slow, so that concurrent prepares overlap. The
+// first statement created for the query "block" waits until the client
+// gives up.
+type PrepareDedupTestServer struct {
+	flightsql.BaseServer
+
+	creates int32
+	closes  int32
+}
+
+func (srv *PrepareDedupTestServer) CreatePreparedStatement(ctx context.Context, req flightsql.ActionCreatePreparedStatementRequest) (res flightsql.ActionCreatePreparedStatementResult, err error) {
+	n := atomic.AddInt32(&srv.creates, 1)
+	if req.GetQuery() == "block" && n == 1 {
+		<-ctx.Done()
+		return res, status.FromContextError(ctx.Err()).Err()
+	}
+	time.Sleep(100 * time.Millisecond)
+	res.Handle = []byte(fmt.Sprintf("%s#%d", req.GetQuery(), n))
+	res.ParameterSchema = arrow.NewSchema([]arrow.Field{{Name: "p", Type: arrow.PrimitiveTypes.Int64, Nullable: true}}, nil)
+	return res, nil
+}
+
+func (srv *PrepareDedupTestServer) ClosePreparedStatement(context.Context, flightsql.ActionClosePreparedStatementRequest) error {
+	atomic.AddInt32(&srv.closes, 1)
+	return nil
+}
+
+type PrepareDedupTestSuite struct {
+	suite.Suite
+
+	srv  *PrepareDedupTestServer
+	s    flight.Server
+	cnxn adbc.Connection
+}
+
+func (suite *PrepareDedupTestSuite) SetupTest() {
+	suite.srv = &PrepareDedupTestServer{}
+	s := flight.NewServerWithMiddleware(nil)
+	s.RegisterFlightService(flightsql.NewFlightServer(suite.srv))
+	suite.Require().NoError(s.Init("localhost:0"))
+	go func() {
+		_ = s.Serve()
+	}()
+	suite.s = s
+
+	db, err := (driver.Driver{}).NewDatabase(map[string]string{
+		adbc.OptionKeyURI: "grpc+tcp://" + s.Addr().String(),
+	})
+	suite.Require().NoError(err)
+	suite.cnxn, err = db.Open(context.Background())
+	suite.Require().NoError(err)
+}
+
+func (suite *PrepareDedupTestSuite) TearDownTest() {
+	suite.Require().NoError(suite.cnxn.Close())
+	suite.s.Shutdown()
+}
+
+func (suite *PrepareDedupTestSuite) newStatements(n int, query string) []adbc.Statement {
+	stmts := make([]adbc.Statement, n)
+	for i := range stmts {
+		stmt, err := suite.cnxn.NewStatement()
+		suite.Require().NoError(err)
+		suite.Require().NoError(stmt.SetSqlQuery(query))
+		stmts[i] = stmt
+	}
+	return stmts
+}
+
+// prepareAll prepares the statements in the background and returns a
+// channel that receives one error per statement.
+func prepareAll(ctx context.Context, stmts []adbc.Statement) <-chan error {
+	errs := make(chan error, len(stmts))
+	for _, stmt := range stmts {
+		go func(stmt adbc.Statement) {
+			errs <- stmt.Prepare(ctx)
+		}(stmt)
+	}
+	return errs
+}
+
+// prepareConcurrently prepares n statements for the same query at the
+// same time.
+func (suite *PrepareDedupTestSuite) prepareConcurrently(n int) []adbc.Statement {
+	stmts := suite.newStatements(n, "SELECT * FROM t WHERE a = ?")
+	errs := prepareAll(context.Background(), stmts)
+	for range stmts {
+		suite.Require().NoError(<-errs)
+	}
+	return stmts
+}
+
+func (suite *PrepareDedupTestSuite) TestConcurrentPrepare() {
+	stmts := suite.prepareConcurrently(50)
+	suite.EqualValues(1, atomic.LoadInt32(&suite.srv.creates))
+
+	// the shared handle is closed once, by the last statement
+	for _, stmt := range stmts {
+		suite.Require().NoError(stmt.Close())
+	}
+	suite.waitForCloses(1)
+}
+
+// waitForCloses waits for the server to see n closed prepared
+// statements, since the client does not wait for the server to handle
+// ClosePreparedStatement, and checks that no more follow.
+func (suite *PrepareDedupTestSuite) waitForCloses(n int32) {
+	suite.Eventually(func() bool {
+		return atomic.LoadInt32(&suite.srv.closes) >= n
+	}, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	suite.Equal(n, atomic.LoadInt32(&suite.srv.closes))
+}
+
+func (suite *PrepareDedupTestSuite) TestFirstCallerCancelled() {
+	stmts := suite.newStatements(6, "block")
+	defer func() {
+		for _, stmt := range stmts {
+			suite.NoError(stmt.Close())
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	first := prepareAll(ctx, stmts[:1])
+	suite.Require().Eventually(func() bool {
+		return atomic.LoadInt32(&suite.srv.creates) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	others := prepareAll(context.Background(), stmts[1:])
+	// give the others time to join the first request
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	var adbcErr adbc.Error
+	suite.Require().ErrorAs(<-first, &adbcErr)
+	suite.Equal(adbc.StatusCancelled, adbcErr.Code)
+
+	// the others are not failed by the first caller's context, and
+	// share a single new request
+	for range stmts[1:] {
+		suite.NoError(<-others)
+	}
+	suite.EqualValues(2, atomic.LoadInt32(&suite.srv.creates))
+}
+
+func (suite *PrepareDedupTestSuite) TestJoinedCallerCancelled() {
+	stmts := suite.newStatements(2, "SELECT 1")
+
+	first := prepareAll(context.Background(), stmts[:1])
+	suite.Require().Eventually(func() bool {
+		return atomic.LoadInt32(&suite.srv.creates) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// a joined caller stops waiting when its own context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var adbcErr adbc.Error
+	suite.Require().ErrorAs(stmts[1].Prepare(ctx), &adbcErr)
+	suite.Equal(adbc.StatusCancelled, adbcErr.Code)
+
+	suite.Require().NoError(<-first)
+	suite.EqualValues(1, atomic.LoadInt32(&suite.srv.creates))
+
+	// ...and no longer holds a reference to the handle
+	suite.Require().NoError(stmts[0].Close())
+	suite.Require().NoError(stmts[1].Close())
+	suite.waitForCloses(1)
+}
+
+func (suite *PrepareDedupTestSuite) TestBindSharedHandle() {
+	stmts := suite.prepareConcurrently(2)
+	suite.EqualValues(1, atomic.LoadInt32(&suite.srv.creates))
+
+	// binding parameters to a shared handle prepares a separate one, so
+	// that they do not affect the other statement
+	bldr := array.NewRecordBuilder(memory.DefaultAllocator, arrow.NewSchema([]arrow.Field{{Name: "p", Type: arrow.PrimitiveTypes.Int64, Nullable: true}}, nil))
+	defer bldr.Release()
+	bldr.Field(0).(*array.Int64Builder).Append(1)
+	rec := bldr.NewRecord()
+	suite.Require().NoError(stmts[0].Bind(context.Background(), rec))
+	suite.EqualValues(2, atomic.LoadInt32(&suite.srv.creates))
+
+	// the remaining statement now owns the original handle and can
+	// bind to it directly
+	bldr.Field(0).(*array.Int64Builder).Append(2)
+	rec = bldr.NewRecord()
+	suite.Require().NoError(stmts[1].Bind(context.Background(), rec))
+	suite.EqualValues(2, atomic.LoadInt32(&suite.srv.creates))
+
+	suite.Require().NoError(stmts[0].Close())
+	suite.Require().NoError(stmts[1].Close())
+	suite.waitForCloses(2)
+}
+
+type countingListener struct {
+	net.Listener
+
+	mu       sync.Mutex
+	accepted int
+	open     int
+}
+
+func (l *countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	l.mu.Lock()
+	l.accepted++
+	l.open++
+	l.mu.Unlock()
+	return &countingConn{Conn: conn, lis: l}, nil
+}
+
+func (l *countingListener) Accepted() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.accepted
+}
+
+// Open returns the number of accepted connections which the server has
+// not closed yet.
+func (l *countingListener) Open() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.open
+}
+
+type countingConn struct {
+	net.Conn
+
+	lis       *countingListener
+	closeOnce sync.Once
+}
+
+func (c *countingConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.lis.mu.Lock()
+		c.lis.open--
+		c.lis.mu.Unlock()
+	})
+	return c.Conn.Close()
+}
+
+// headerRecorder records the values of the x-header header of each
+// call received by the server.
+type headerRecorder struct {
+	mu    sync.Mutex
+	calls [][]string
+}
+
+func (h *headerRecorder) StartCall(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	h.mu.Lock()
+	h.calls = append(h.calls, md.Get("x-header"))
+	h.mu.Unlock()
+	return ctx
+}
+
+func (h *headerRecorder) CallCompleted(context.Context, error) {}
+
+// Take returns the calls recorded since the last call to Take.
+func (h *headerRecorder) Take() [][]string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	calls := h.calls
+	h.calls = nil
+	return calls
+}
+
+type SharedChannelTestSuite struct {
+	suite.Suite
+
+	lis      *countingListener
+	recorder *headerRecorder
+	s        flight.Server
+	uri      string
+}
+
+func (suite *SharedChannelTestSuite) SetupTest() {
+	lis, err := net.Listen("tcp", "localhost:0")
+	suite.Require().NoError(err)
+	suite.lis = &countingListener{Listener: lis}
+	suite.recorder = &headerRecorder{}
+
+	s := flight.NewServerWithMiddleware([]flight.ServerMiddleware{flight.CreateServerMiddleware(suite.recorder)})
+	s.RegisterFlightService(flightsql.NewFlightServer(&LocationTestServer{}))
+	s.InitListener(suite.lis)
+	go func() {
+		_ = s.Serve()
+	}()
+	suite.s = s
+
+	suite.uri = "grpc+tcp://" + suite.s.Addr().String()
+}
+
+func (suite *SharedChannelTestSuite) TearDownTest() {
+	suite.s.Shutdown()
+}
+
+func (suite *SharedChannelTestSuite) query(cnxn adbc.Connection) {
+	stmt, err := cnxn.NewStatement()
+	suite.Require().NoError(err)
+	defer stmt.Close()
+
+	suite.Require().NoError(stmt.SetSqlQuery("SELECT 42"))
+	rdr, _, err := stmt.ExecuteQuery(context.Background())
+	suite.Require().NoError(err)
+	defer rdr.Release()
+
+	for rdr.Next() {
+	}
+	suite.Require().NoError(rdr.Err())
+}
+
+func (suite *SharedChannelTestSuite) openConnections(opts map[string]string) (adbc.Connection, adbc.Connection) {
+	opts[adbc.OptionKeyURI] = suite.uri
+	db, err := (driver.Driver{}).NewDatabase(opts)
+	suite.Require().NoError(err)
+
+	first, err := db.Open(context.Background())
+	suite.Require().NoError(err)
+	second, err := db.Open(context.Background())
+	suite.Require().NoError(err)
+
+	suite.query(first)
+	suite.query(second)
+	return first, second
+}
+
+func (suite *SharedChannelTestSuite) TestSeparateChannels() {
+	first, second := suite.openConnections(map[string]string{})
+	defer first.Close()
+	defer second.Close()
+
+	suite.Equal(2, suite.lis.Accepted())
+}
+
+func (suite *SharedChannelTestSuite) TestSharedChannel() {
+	first, second := suite.openConnections(map[string]string{
+		driver.OptionChannelShared: adbc.OptionValueEnabled,
+	})
+	defer second.Close()
+
+	suite.Equal(1, suite.lis.Accepted())
+
+	// closing one connection must not close the channel for the other
+	suite.Require().NoError(first.Close())
+	suite.query(second)
+	suite.Equal(1, suite.lis.Accepted())
+}
+
+func (suite *SharedChannelTestSuite) TestSharedChannelHeaders() {
+	first, second := suite.openConnections(map[string]string{
+		driver.OptionChannelShared: adbc.OptionValueEnabled,
+	})
+	defer first.Close()
+	defer second.Close()
+	suite.Equal(1, suite.lis.Accepted())
+
+	suite.Require().NoError(first.(adbc.PostInitOptions).
+		SetOption("adbc.flight.sql.rpc.call_header.x-header", "first"))
+	suite.Require().NoError(second.(adbc.PostInitOptions).
+		SetOption("adbc.flight.sql.rpc.call_header.x-header", "second"))
+	suite.recorder.Take()
+
+	// each connection's calls carry only its own header, even though
+	// they are sent over the same channel
+	for _, tc := range []struct {
+		cnxn   adbc.Connection
+		header string
+	}{{first, "first"}, {second, "second"}, {first, "first"}} {
+		suite.query(tc.cnxn)
+		calls := suite.recorder.Take()
+		suite.Require().NotEmpty(calls)
+		for _, values := range calls {
+			suite.Equal([]string{tc.header}, values)
+		}
+	}
+	suite.Equal(1, suite.lis.Accepted())
+}
+
+func (suite *SharedChannelTestSuite) TestIdleTimeout() {
+	db, err := (driver.Driver{}).NewDatabase(map[string]string{
+		adbc.OptionKeyURI:               suite.uri,
+		driver.OptionChannelShared:      adbc.OptionValueEnabled,
+		driver.OptionChannelIdleTimeout: "0.2",
+	})
+	suite.Require().NoError(err)
+
+	cnxn, err := db.Open(context.Background())
+	suite.Require().NoError(err)
+	suite.query(cnxn)
+	suite.Require().NoError(cnxn.Close())
+
+	// a connection opened before the timeout reuses the idle channel
+	cnxn, err = db.Open(context.Background())
+	suite.Require().NoError(err)
+	suite.query(cnxn)
+	suite.Require().NoError(cnxn.Close())
+	suite.Equal(1, suite.lis.Accepted())
+
+	// the unused channel is closed after the timeout...
+	suite.Eventually(func() bool {
+		return suite.lis.Open() == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// ...and dialed again by the next connection
+	cnxn, err = db.Open(context.Background())
+	suite.Require().NoError(err)
+	defer cnxn.Close()
+	suite.query(cnxn)
+	suite.Equal(2, suite.lis.Accepted())
+}
+
+func (suite *SharedChannelTestSuite) TestInvalidOption() {
+	_, err := (driver.Driver{}).NewDatabase(map[string]string{
+		adbc.OptionKeyURI:          suite.uri,
+		driver.OptionChannelShared: "invalid",
+	})
+	suite.ErrorContains(err, "Invalid value for database option 'adbc.flight.sql.channel.shared': 'invalid'")
+
+	_, err = (driver.Driver{}).NewDatabase(map[string]string{
+		adbc.OptionKeyURI:               suite.uri,
+		driver.OptionChannelIdleTimeout: "-1",
+	})
+	suite.ErrorContains(err, "invalid timeout option value adbc.flight.sql.channel.idle_timeout = -1")
+}
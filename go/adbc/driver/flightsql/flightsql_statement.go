@@ -86,11 +86,15 @@ func (s *sqlOrSubstrait) executeUpdate(ctx context.Context, cnxn *cnxn, opts ...
 	}
 }
 
-func (s *sqlOrSubstrait) prepare(ctx context.Context, cnxn *cnxn, opts ...grpc.CallOption) (*flightsql.PreparedStatement, error) {
+func (s *sqlOrSubstrait) prepare(ctx context.Context, cnxn *cnxn, opts ...grpc.CallOption) (*sharedPrepared, error) {
 	if s.sqlQuery != "" {
-		return cnxn.prepare(ctx, s.sqlQuery, opts...)
+		return cnxn.prepareShared(ctx, s.sqlQuery, opts...)
 	} else if s.substraitPlan != nil {
-		return cnxn.prepareSubstrait(ctx, flightsql.SubstraitPlan{Plan: s.substraitPlan, Version: s.substraitVersion}, opts...)
+		prep, err := cnxn.prepareSubstrait(ctx, flightsql.SubstraitPlan{Plan: s.substraitPlan, Version: s.substraitVersion}, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return newPrepared(prep), nil
 	}
 
 	return nil, adbc.Error{
@@ -99,6 +103,26 @@ func (s *sqlOrSubstrait) prepare(ctx context.Context, cnxn *cnxn, opts ...grpc.C
 	}
 }
 
+// Statement is implemented by statements created by this driver and
+// exposes Flight SQL functionality that is not part of the generic ADBC
+// API. Callers can access it with a type assertion on an adbc.Statement.
+type Statement interface {
+	adbc.Statement
+
+	// ResultSize returns the total number of records and bytes that the
+	// server reported in the FlightInfo for the most recently executed
+	// query, e.g. for use as a progress denominator. Either value is -1
+	// if the server did not report it or no query has been executed.
+	ResultSize() (totalRecords, totalBytes int64)
+
+	// ExecuteSchema returns the schema of the result set of the query
+	// without fetching any data. For a prepared statement, the schema
+	// returned by the server when preparing it is used if available.
+	// Otherwise the schema is read from the FlightInfo for the query,
+	// and an error is returned if the server did not include one.
+	ExecuteSchema(ctx context.Context) (*arrow.Schema, error)
+}
+
 type statement struct {
 	alloc       memory.Allocator
 	cnxn        *cnxn
@@ -106,13 +130,52 @@ type statement struct {
 
 	hdrs      metadata.MD
 	query     sqlOrSubstrait
-	prepared  *flightsql.PreparedStatement
+	prepared  *sharedPrepared
 	queueSize int
 	timeouts  timeoutOption
+
+	totalRecords int64
+	totalBytes   int64
+}
+
+func (s *statement) ResultSize() (totalRecords, totalBytes int64) {
+	return s.totalRecords, s.totalBytes
+}
+
+func (s *statement) setResultSize(info *flight.FlightInfo) {
+	if info == nil {
+		s.totalRecords, s.totalBytes = -1, -1
+		return
+	}
+	s.totalRecords, s.totalBytes = info.TotalRecords, info.TotalBytes
 }
 
 func (s *statement) closePreparedStatement() error {
-	return s.prepared.Close(context.Background(), s.timeouts)
+	return s.prepared.release(context.Background(), s.timeouts)
+}
+
+// exclusivePrepared makes sure this statement is the only user of its
+// prepared statement handle before parameters are bound to it, replacing
+// a handle shared with other statements by a newly prepared one.
+func (s *statement) exclusivePrepared(ctx context.Context) error {
+	if s.prepared.exclusive() {
+		return nil
+	}
+
+	ctx = metadata.NewOutgoingContext(ctx, s.hdrs)
+	prep, err := s.cnxn.prepare(ctx, s.query.sqlQuery, s.timeouts)
+	if err != nil {
+		return adbcFromFlightStatus(err)
+	}
+
+	// the other statements may have released the shared handle in the
+	// meantime, in which case this closes it
+	err = s.closePreparedStatement()
+	s.prepared = newPrepared(prep)
+	if err != nil {
+		return adbcFromFlightStatus(err)
+	}
+	return nil
 }
 
 // Close releases any relevant resources associated with this statement
@@ -237,16 +300,56 @@ func (s *statement) ExecuteQuery(ctx context.Context) (rdr array.RecordReader, n
 	} else {
 		info, err = s.query.execute(ctx, s.cnxn, s.timeouts)
 	}
+	s.setResultSize(info)
 
 	if err != nil {
 		return nil, -1, adbcFromFlightStatus(err)
 	}
 
 	nrec = info.TotalRecords
-	rdr, err = newRecordReader(ctx, s.alloc, s.cnxn.cl, info, s.clientCache, s.queueSize, s.timeouts)
+	rdr, err = newRecordReader(ctx, s.alloc, s.cnxn.cl, info, s.clientCache, s.queueSize, s.timeouts, s.cnxn.db.locationPref)
 	return
 }
 
+func (s *statement) ExecuteSchema(ctx context.Context) (*arrow.Schema, error) {
+	if s.prepared != nil {
+		if schema := s.prepared.DatasetSchema(); schema != nil {
+			return schema, nil
+		}
+	}
+
+	ctx = metadata.NewOutgoingContext(ctx, s.hdrs)
+	var (
+		info *flight.FlightInfo
+		err  error
+	)
+	if s.prepared != nil {
+		info, err = s.prepared.Execute(ctx, s.timeouts)
+	} else {
+		info, err = s.query.execute(ctx, s.cnxn, s.timeouts)
+	}
+
+	if err != nil {
+		return nil, adbcFromFlightStatus(err)
+	}
+
+	if len(info.Schema) == 0 {
+		return nil, adbc.Error{
+			Msg:  "[Flight SQL] Server returned FlightInfo with no schema",
+			Code: adbc.StatusInternal,
+		}
+	}
+
+	schema, err := flight.DeserializeSchema(info.Schema, s.alloc)
+	if err != nil {
+		return nil, adbc.Error{
+			Msg:  err.Error(),
+			Code: adbc.StatusInternal,
+		}
+	}
+	return schema, nil
+}
+
 // ExecuteUpdate executes a statement that does not generate a result
 // set. It returns the number of rows affected if known, otherwise -1.
 func (s *statement) ExecuteUpdate(ctx context.Context) (int64, error) {
@@ -266,7 +369,14 @@ func (s *statement) Prepare(ctx context.Context) error {
 	if err != nil {
 		return adbcFromFlightStatus(err)
 	}
+	if s.prepared != nil {
+		// drop this statement's reference to its previous handle
+		err = s.closePreparedStatement()
+	}
 	s.prepared = prep
+	if err != nil {
+		return adbcFromFlightStatus(err)
+	}
 	return nil
 }
 
@@ -297,7 +407,7 @@ func (s *statement) SetSubstraitPlan(plan []byte) error {
 // The driver will call release on the passed in Record when it is done,
 // but it may not do this until the statement is closed or another
 // record is bound.
-func (s *statement) Bind(_ context.Context, values arrow.Record) error {
+func (s *statement) Bind(ctx context.Context, values arrow.Record) error {
 	// TODO: handle bulk insert situation
 
 	if s.prepared == nil {
@@ -306,6 +416,10 @@ func (s *statement) Bind(_ context.Context, values arrow.Record) error {
 			Code: adbc.StatusInvalidState}
 	}
 
+	if err := s.exclusivePrepared(ctx); err != nil {
+		return err
+	}
+
 	s.prepared.SetParameters(values)
 	return nil
 }
@@ -315,13 +429,17 @@ func (s *statement) Bind(_ context.Context, values arrow.Record) error {
 //
 // The driver will call Release on the record reader, but may not do this
 // until Close is called.
-func (s *statement) BindStream(_ context.Context, stream array.RecordReader) error {
+func (s *statement) BindStream(ctx context.Context, stream array.RecordReader) error {
 	if s.prepared == nil {
 		return adbc.Error{
 			Msg:  "[Flight SQL Statement] must call Prepare before calling Bind",
 			Code: adbc.StatusInvalidState}
 	}
 
+	if err := s.exclusivePrepared(ctx); err != nil {
+		return err
+	}
+
 	s.prepared.SetRecordReader(stream)
 	return nil
 }
@@ -383,6 +501,7 @@ func (s *statement) ExecutePartitions(ctx context.Context) (*arrow.Schema, adbc.
 	} else {
 		info, err = s.query.execute(ctx, s.cnxn, s.timeouts)
 	}
+	s.setResultSize(info)
 
 	if err != nil {
 		return nil, out, -1, adbcFromFlightStatus(err)
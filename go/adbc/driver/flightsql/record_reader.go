@@ -19,7 +19,9 @@ package flightsql
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"sync/atomic"
 
 	"github.com/apache/arrow-adbc/go/adbc"
@@ -27,6 +29,7 @@ import (
 	"github.com/apache/arrow/go/v12/arrow/array"
 	"github.com/apache/arrow/go/v12/arrow/flight"
 	"github.com/apache/arrow/go/v12/arrow/flight/flightsql"
+	"github.com/apache/arrow/go/v12/arrow/ipc"
 	"github.com/apache/arrow/go/v12/arrow/memory"
 	"github.com/bluele/gcache"
 	"golang.org/x/sync/errgroup"
@@ -118,17 +121,21 @@ func newRecordReader(ctx context.Context, alloc memory.Allocator, cl *flightsql.
 	lastChannelIndex := len(chs) - 1
 
 	referenceSchema := removeSchemaMetadata(schema)
+	var prevOrder *streamOrder
 	for i, ep := range endpoints {
 		endpoint := ep
 		endpointIndex := i
 		chs[endpointIndex] = make(chan arrow.Record, bufferSize)
+		streamCtx, order := withStreamOrder(ctx, prevOrder)
+		prevOrder = order
 		group.Go(func() error {
 			// Close channels (except the last) so that Next can move on to the next channel properly
 			if endpointIndex != lastChannelIndex {
 				defer close(chs[endpointIndex])
 			}
 
-			rdr, err := doGet(ctx, cl, endpoint, clCache, opts...)
+			rdr, err := doGet(streamCtx, cl, endpoint, clCache, opts...)
+			order.granted()
 			if err != nil {
 				return err
 			}
@@ -255,3 +262,93 @@ func removeFieldMetadata(field *arrow.Field) arrow.Field {
 		Metadata: arrow.Metadata{},
 	}
 }
+
+// exchangeReader reads the response of a DoExchange call, reporting
+// errors from writing the request stream once the response is consumed.
+type exchangeReader struct {
+	*flight.Reader
+
+	refCount int64
+	cancelFn context.CancelFunc
+	writeErr <-chan error
+	err      error
+}
+
+func writeExchange(stream flight.FlightService_DoExchangeClient, desc *flight.FlightDescriptor, input array.RecordReader) error {
+	wr := flight.NewRecordWriter(stream, ipc.WithSchema(input.Schema()))
+	wr.SetFlightDescriptor(desc)
+	for input.Next() {
+		if err := wr.Write(input.Record()); err != nil {
+			return sendError(err)
+		}
+	}
+	if err := input.Err(); err != nil {
+		return err
+	}
+	if err := wr.Close(); err != nil {
+		return sendError(err)
+	}
+	return stream.CloseSend()
+}
+
+// sendError ignores io.EOF, which gRPC returns from Send when the
+// server ended the stream; the actual status is returned by Recv.
+func sendError(err error) error {
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+func (r *exchangeReader) Retain() {
+	atomic.AddInt64(&r.refCount, 1)
+}
+
+func (r *exchangeReader) Release() {
+	if atomic.AddInt64(&r.refCount, -1) == 0 {
+		r.Reader.Release()
+		r.cancelFn()
+	}
+}
+
+func (r *exchangeReader) Next() bool {
+	if r.Reader.Next() {
+		return true
+	}
+	if r.writeErr != nil {
+		r.err = <-r.writeErr
+		r.writeErr = nil
+	}
+	return false
+}
+
+func (r *exchangeReader) Err() error {
+	if r.err != nil {
+		return adbcFromFlightStatus(r.err)
+	}
+	if err := r.Reader.Err(); err != nil {
+		return adbcFromFlightStatus(err)
+	}
+	return nil
+}
+
+// doGetReader is the result of a DoGet call. Releasing it cancels the
+// call, so that a stream which is not read to the end does not stay
+// open (and keep its slot in a streamLimiter).
+type doGetReader struct {
+	*flight.Reader
+
+	refCount int64
+	cancelFn context.CancelFunc
+}
+
+func (r *doGetReader) Retain() {
+	atomic.AddInt64(&r.refCount, 1)
+}
+
+func (r *doGetReader) Release() {
+	if atomic.AddInt64(&r.refCount, -1) == 0 {
+		r.Reader.Release()
+		r.cancelFn()
+	}
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/apache/arrow/go/v12/arrow/ipc"
 	"github.com/apache/arrow/go/v12/arrow/memory"
 	"github.com/bluele/gcache"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
@@ -365,3 +366,36 @@ func (suite *RecordReaderTests) TestOrdering() {
 func TestRecordReader(t *testing.T) {
 	suite.Run(t, &RecordReaderTests{})
 }
+
+func TestLocationPreferenceOrder(t *testing.T) {
+	locations := []*flight.Location{
+		{Uri: "grpc://plain-1:1"},
+		{Uri: "grpc+tls://tls-1:1"},
+		{Uri: "grpc+unix:///tmp/socket"},
+		{Uri: "grpc://plain-2:1"},
+		{Uri: "grpc+tls://tls-2:1"},
+	}
+	uris := func(locs []*flight.Location) []string {
+		out := make([]string, len(locs))
+		for i, loc := range locs {
+			out[i] = loc.Uri
+		}
+		return out
+	}
+
+	pref := locationPreference{schemes: []string{"grpc+tls", "grpc"}}
+	assert.Equal(t, []string{"grpc+tls://tls-1:1", "grpc+tls://tls-2:1", "grpc://plain-1:1", "grpc://plain-2:1"},
+		uris(pref.order(locations)))
+
+	pref = locationPreference{schemes: []string{"grpc+tls"}}
+	assert.Equal(t, []string{"grpc+tls://tls-1:1", "grpc+tls://tls-2:1"}, uris(pref.order(locations)))
+
+	pref = locationPreference{schemes: []string{"grpc+tcp"}}
+	assert.Empty(t, pref.order(locations))
+
+	_, ok := getLocationPreference([]grpc.CallOption{timeoutOption{}, locationPreference{}})
+	assert.False(t, ok)
+	got, ok := getLocationPreference([]grpc.CallOption{timeoutOption{}, pref})
+	assert.True(t, ok)
+	assert.Equal(t, pref, got)
+}
@@ -61,3 +61,15 @@ func adbcFromFlightStatus(err error) error {
 		Code: adbcCode,
 	}
 }
+
+func isPrintableASCII(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}